@@ -0,0 +1,76 @@
+// Command fieldgen generates typed field constructors from a schema of
+// well-known keys, as declared by the schema package. It's meant to be run by
+// go generate, from the package holding the schema:
+//
+//	//go:generate go run github.com/emiguens/zapfmt/cmd/fieldgen -o keys.go schema.json
+//
+// Usage:
+//
+//	fieldgen [-package name] [-o file] schema.json
+//
+// The package name defaults to the one go generate runs for, and the code is
+// written to standard output if no file is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/emiguens/zapfmt/schema"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command, returning the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fieldgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	pkg := fs.String("package", os.Getenv("GOPACKAGE"), "`name` of the generated package")
+	out := fs.String("o", "", "write the code to `file` instead of standard output")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: fieldgen [-package name] [-o file] schema.json")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *pkg == "" {
+		fs.Usage()
+		return 2
+	}
+
+	if err := generate(fs.Arg(0), *pkg, *out, stdout); err != nil {
+		fmt.Fprintf(stderr, "fieldgen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func generate(path, pkg, out string, stdout io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s, err := schema.Parse(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	src, err := schema.Generate(s, pkg, filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	if out == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
@@ -0,0 +1,23 @@
+//go:build go1.22
+// +build go1.22
+
+// Command logcheck reports mistakes in the logging calls made through
+// github.com/emiguens/zapfmt, such as logging with a context that holds no
+// logger. See the logcheck package for what it reports.
+//
+// It runs on its own or through go vet, which runs it package by package:
+//
+//	logcheck ./...
+//	go vet -vettool=$(which logcheck) ./...
+//
+// Run it with -fix to apply the suggested fixes.
+package main
+
+import (
+	"github.com/emiguens/zapfmt/logcheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(logcheck.Analyzer)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+// Malformed line handling modes.
+const (
+	malformedPass = "pass"
+	malformedTag  = "tag"
+	malformedSkip = "skip"
+)
+
+// runConvert converts lines from one log format to another, one at a time so
+// that files of any size are converted in constant memory.
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	from := fs.String("from", "kv", "format of the input: kv, json or logfmt")
+	to := fs.String("to", "json", "format of the output: kv, json or logfmt")
+	malformed := fs.String("malformed", malformedPass, "what to do with lines that can't be parsed: pass them through, tag them as a record with a malformed key, or skip them")
+	maxLine := fs.Int("max-line", kvparse.DefaultMaxLineSize, "longest line `size` read whole, in bytes")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: zapfmt convert [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	in, err := lookupFormat(*from)
+	if err != nil {
+		return err
+	}
+	out, err := lookupFormat(*to)
+	if err != nil {
+		return err
+	}
+	switch *malformed {
+	case malformedPass, malformedTag, malformedSkip:
+	default:
+		return fmt.Errorf("unknown malformed mode %q, must be one of pass, tag, skip", *malformed)
+	}
+
+	w := bufio.NewWriter(stdout)
+	var buf []byte
+	err = eachInput(fs.Args(), stdin, func(name string, r io.Reader) error {
+		sc := kvparse.NewScanner(r)
+		sc.MaxLineSize = *maxLine
+		for n := 1; sc.Scan(); n++ {
+			if sc.Truncated() {
+				warnTruncated(stderr, "convert", name, n, sc)
+			}
+			buf = buf[:0]
+			rec, err := in.parse(sc.Line())
+			switch {
+			case err == nil:
+				buf = out.append(buf, rec)
+			case *malformed == malformedPass:
+				buf = append(buf, sc.Line()...)
+			case *malformed == malformedTag:
+				buf = out.append(buf, kvparse.Record{Fields: []kvparse.Field{
+					{Key: "malformed", Value: kvparse.Value{Str: string(sc.Line())}},
+				}})
+			default:
+				continue
+			}
+			buf = append(buf, '\n')
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("reading %s: %v", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	const input = "[ts:2019-04-08T20:21:32.375067Z][level:info][msg:started][port:8080]\n" +
+		"panic: something went wrong\n" +
+		"[ts:2019-04-08T20:21:33.375067Z][level:error][msg:failed][user:{name:jane}]\n"
+
+	tt := []struct {
+		Name     string
+		Args     []string
+		Input    string
+		Expected string
+	}{
+		{
+			Name:  "To JSON By Default",
+			Input: input,
+			Expected: `{"ts":"2019-04-08T20:21:32.375067Z","level":"info","msg":"started","port":8080}` + "\n" +
+				"panic: something went wrong\n" +
+				`{"ts":"2019-04-08T20:21:33.375067Z","level":"error","msg":"failed","user":{"name":"jane"}}` + "\n",
+		},
+		{
+			Name:  "To Logfmt Tagging Malformed Lines",
+			Args:  []string{"-to", "logfmt", "-malformed", "tag"},
+			Input: input,
+			Expected: "ts=2019-04-08T20:21:32.375067Z level=info msg=started port=8080\n" +
+				`malformed="panic: something went wrong"` + "\n" +
+				"ts=2019-04-08T20:21:33.375067Z level=error msg=failed user.name=jane\n",
+		},
+		{
+			Name:     "Skipping Malformed Lines",
+			Args:     []string{"-to", "kv", "-malformed", "skip"},
+			Input:    input,
+			Expected: "[ts:2019-04-08T20:21:32.375067Z][level:info][msg:started][port:8080]\n[ts:2019-04-08T20:21:33.375067Z][level:error][msg:failed][user:{name:jane}]\n",
+		},
+		{
+			Name:     "From JSON",
+			Args:     []string{"-from", "json", "-to", "kv", "-"},
+			Input:    `{"level":"warn","msg":"slow","elapsed":1.5}` + "\n",
+			Expected: "[level:warn][msg:slow][elapsed:1.5]\n",
+		},
+		{
+			Name:     "From Logfmt",
+			Args:     []string{"-from", "logfmt"},
+			Input:    `level=warn msg="slow query" elapsed=1.5` + "\n",
+			Expected: `{"level":"warn","msg":"slow query","elapsed":1.5}` + "\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"convert"}, tc.Args...), strings.NewReader(tc.Input), &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tc.Expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.Expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestConvertTruncated(t *testing.T) {
+	input := "[msg:short]\n[msg:" + strings.Repeat("x", 20) + "]\n"
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"convert", "-to", "kv", "-max-line", "16"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+	}
+	if expected := "[msg:short]\n[msg:xxxxxxxxxxx\n"; stdout.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, stdout.String())
+	}
+	if expected := "zapfmt convert: -:2: line longer than 16 bytes, truncated\n"; stderr.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, stderr.String())
+	}
+}
+
+func TestConvertFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	if err := os.WriteFile(a, []byte("[msg:a]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("[msg:b]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"convert", a, b}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+	}
+	if expected := "{\"msg\":\"a\"}\n{\"msg\":\"b\"}\n"; stdout.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, stdout.String())
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Args   []string
+		Code   int
+		Stderr string
+	}{
+		{
+			Name:   "Unknown Command",
+			Args:   []string{"nope"},
+			Code:   2,
+			Stderr: `zapfmt: unknown command "nope"`,
+		},
+		{
+			Name:   "Unknown Flag",
+			Args:   []string{"convert", "-nope"},
+			Code:   2,
+			Stderr: "flag provided but not defined: -nope",
+		},
+		{
+			Name:   "Unknown Format",
+			Args:   []string{"convert", "-to", "xml"},
+			Code:   1,
+			Stderr: `zapfmt convert: unknown format "xml", must be one of json, kv, logfmt`,
+		},
+		{
+			Name:   "Missing File",
+			Args:   []string{"convert", "missing.log"},
+			Code:   1,
+			Stderr: "zapfmt convert: open missing.log: no such file or directory",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.Args, strings.NewReader(""), &stdout, &stderr); code != tc.Code {
+				t.Fatalf("expected exit code %d, got %d", tc.Code, code)
+			}
+			if !strings.Contains(stderr.String(), tc.Stderr) {
+				t.Fatalf("expected stderr to contain %q, got %q", tc.Stderr, stderr.String())
+			}
+		})
+	}
+}
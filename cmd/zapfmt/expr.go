@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/emiguens/zapfmt/kvparse"
+	"go.uber.org/zap/zapcore"
+)
+
+// filter reports whether a record matches an expression.
+type filter func(kvparse.Record) bool
+
+// parseFilter compiles a filter expression, such as
+//
+//	level>=warn && request_id=="abc" && (duration>0.5 || msg=~"time(d )?out")
+//
+// Comparisons take a key on the left, dotted to look into namespaces, and a
+// quoted string, number, bool or bare word on the right. Operators are ==,
+// !=, <, <=, > and >=, plus =~ and !~ to match regular expressions. Levels
+// are compared by severity, ts by time, and values are compared as numbers
+// when both sides are numbers or durations. A key alone matches records
+// having it, and comparisons on missing keys never match.
+//
+// Comparisons are combined with &&, || and !, and grouped with parentheses.
+func parseFilter(expr string) (filter, error) {
+	tokens := tokenize(expr)
+	if t := tokens[len(tokens)-1]; t.kind == tokError {
+		return nil, fmt.Errorf("%s at offset %d", t.text, t.pos)
+	}
+
+	p := &exprParser{tokens: tokens}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+	}
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokError
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators are sorted so that longer ones are tried first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+func tokenize(s string) []token {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+			continue
+		case c == '"':
+			n := 1
+			for ; i+n < len(s) && s[i+n] != '"'; n++ {
+				if s[i+n] == '\\' {
+					n++
+				}
+			}
+			if i+n == len(s) {
+				return append(tokens, token{kind: tokError, text: "unterminated string", pos: i})
+			}
+			str, err := strconv.Unquote(s[i : i+n+1])
+			if err != nil {
+				return append(tokens, token{kind: tokError, text: "invalid string", pos: i})
+			}
+			tokens = append(tokens, token{kind: tokString, text: str, pos: i})
+			i += n + 1
+			continue
+		}
+
+		op := ""
+		for _, o := range operators {
+			if strings.HasPrefix(s[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op != "" {
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+			continue
+		}
+
+		n := strings.IndexFunc(s[i:], func(r rune) bool {
+			return unicode.IsSpace(r) || strings.ContainsRune(`"&|=!<>()`, r)
+		})
+		if n < 0 {
+			n = len(s) - i
+		}
+		if n == 0 {
+			return append(tokens, token{kind: tokError, text: fmt.Sprintf("unexpected %q", s[i]), pos: i})
+		}
+		tokens = append(tokens, token{kind: tokWord, text: s[i : i+n], pos: i})
+		i += n
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)})
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) or() (filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r kvparse.Record) bool { return l(r) || right(r) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) and() (filter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r kvparse.Record) bool { return l(r) && right(r) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) unary() (filter, error) {
+	if p.accept("!") {
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(r kvparse.Record) bool { return !f(r) }, nil
+	}
+	if p.accept("(") {
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			t := p.peek()
+			return nil, fmt.Errorf("expected \")\" at offset %d, got %s", t.pos, t)
+		}
+		return f, nil
+	}
+	return p.comparison()
+}
+
+func (p *exprParser) comparison() (filter, error) {
+	key := p.next()
+	if key.kind != tokWord {
+		return nil, fmt.Errorf("expected a key at offset %d, got %s", key.pos, key)
+	}
+
+	op := p.peek()
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+		if op.kind != tokOp {
+			break
+		}
+		p.next()
+		lit := p.next()
+		if lit.kind != tokWord && lit.kind != tokString {
+			return nil, fmt.Errorf("expected a value at offset %d, got %s", lit.pos, lit)
+		}
+		return compare(key.text, op.text, lit)
+	}
+
+	return func(r kvparse.Record) bool {
+		v, ok := r.Get(key.text)
+		return ok && !(v.Kind == kvparse.BoolKind && v.Str == "false")
+	}, nil
+}
+
+// compare returns the filter comparing the value of key with a literal.
+func compare(key, op string, lit token) (filter, error) {
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(lit.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at offset %d: %v", lit.pos, err)
+		}
+		return func(r kvparse.Record) bool {
+			v, ok := r.Get(key)
+			return ok && re.MatchString(v.String()) == (op == "=~")
+		}, nil
+	}
+
+	var lvl zapcore.Level
+	if key == "level" && lit.text != "" && lvl.UnmarshalText([]byte(strings.ToLower(lit.text))) == nil {
+		return func(r kvparse.Record) bool {
+			l, ok := r.Level()
+			return ok && holds(op, int(l)-int(lvl))
+		}, nil
+	}
+
+	if key == "ts" {
+		if t, err := parseTime(lit.text); err == nil {
+			return func(r kvparse.Record) bool {
+				rt, ok := r.Time()
+				return ok && holds(op, compareTimes(rt, t))
+			}, nil
+		}
+	}
+
+	if n, ok := (kvparse.Value{Str: lit.text}).Float(); ok && lit.kind == tokWord {
+		// Values that aren't numbers are compared as text.
+		return func(r kvparse.Record) bool {
+			v, ok := r.Get(key)
+			if !ok {
+				return false
+			}
+			if f, ok := v.Float(); ok {
+				return holds(op, compareFloats(f, n))
+			}
+			return holds(op, strings.Compare(v.String(), lit.text))
+		}, nil
+	}
+
+	return func(r kvparse.Record) bool {
+		v, ok := r.Get(key)
+		return ok && holds(op, strings.Compare(v.String(), lit.text))
+	}, nil
+}
+
+// holds reports whether op holds for the result of a comparison.
+func holds(op string, cmp int) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+func TestFilter(t *testing.T) {
+	now = func() time.Time { return time.Date(2019, time.April, 8, 20, 30, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	rec, err := kvparse.Parse([]byte(`[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:request timed out][request_id:abc][duration:0.75][elapsed:1.5s][ok:false][http:{status:504][route:/users}][note:say \"hi\"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		Expr     string
+		Expected bool
+	}{
+		{`level>=warn`, true},
+		{`level>=ERROR`, false},
+		{`level==warn && request_id=="abc"`, true},
+		{`request_id==abc`, true},
+		{`request_id!="abc"`, false},
+		{`duration>0.5`, true},
+		{`duration<=0.5`, false},
+		{`elapsed>=1s && elapsed<2`, true},
+		{`http.status>=500`, true},
+		{`http.route=="/users"`, true},
+		{`http.missing==1`, false},
+		{`missing!=1`, false},
+		{`msg=~"time(d )?out"`, true},
+		{`msg!~"^request"`, false},
+		{`note=="say \"hi\""`, true},
+		{`request_id`, true},
+		{`ok`, false},
+		{`!missing`, true},
+		{`level>=error || (duration>0.5 && !ok)`, true},
+		{`!(level>=warn)`, false},
+		{`ts>="2019-04-08T20:21:00Z" && ts<"2019-04-08T20:22:00Z"`, true},
+		{`ts>=5m`, false},
+		{`ts>=10m`, true},
+		{`msg>request`, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Expr, func(t *testing.T) {
+			match, err := parseFilter(tc.Expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := match(rec); got != tc.Expected {
+				t.Fatalf("expected %v, got %v", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	tt := []struct {
+		Expr  string
+		Error string
+	}{
+		{``, "expected a key at offset 0, got end of expression"},
+		{`level>=`, "expected a value at offset 7, got end of expression"},
+		{`(level>=warn`, `expected ")" at offset 12`},
+		{`level>=warn)`, `unexpected ")" at offset 11`},
+		{`msg=="open`, "unterminated string at offset 5"},
+		{`msg=~"("`, "invalid regular expression at offset 5"},
+		{`a && || b`, `expected a key at offset 5, got "||"`},
+		{`a b`, `unexpected "b" at offset 2`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Expr, func(t *testing.T) {
+			_, err := parseFilter(tc.Expr)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("expected error containing %q, got %v", tc.Error, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+// errUsage is returned by commands given invalid flags, once the usage was
+// printed.
+var errUsage = errors.New("invalid usage")
+
+func exitCode(err error) int {
+	if err == errUsage {
+		return 2
+	}
+	return 1
+}
+
+// parseFlags parses the flags of a command, printing errors and the usage to
+// stderr.
+func parseFlags(fs *flag.FlagSet, args []string, stderr io.Writer) error {
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+// format parses and writes the lines of a log format.
+type format struct {
+	parse  func([]byte) (kvparse.Record, error)
+	append func([]byte, kvparse.Record) []byte
+}
+
+var formats = map[string]format{
+	"kv":     {kvparse.Parse, kvparse.AppendKeyValue},
+	"json":   {kvparse.ParseJSON, kvparse.AppendJSON},
+	"logfmt": {kvparse.ParseLogfmt, kvparse.AppendLogfmt},
+}
+
+func lookupFormat(name string) (format, error) {
+	f, ok := formats[name]
+	if !ok {
+		names := make([]string, 0, len(formats))
+		for n := range formats {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return format{}, fmt.Errorf("unknown format %q, must be one of %s", name, strings.Join(names, ", "))
+	}
+	return f, nil
+}
+
+// warnTruncated reports on stderr that the line the scanner just read, the
+// given line of the named input, was cut as it was longer than it reads
+// whole.
+func warnTruncated(stderr io.Writer, cmd, name string, line int, sc *kvparse.Scanner) {
+	max := sc.MaxLineSize
+	if max <= 0 {
+		max = kvparse.DefaultMaxLineSize
+	}
+	fmt.Fprintf(stderr, "zapfmt %s: %s:%d: line longer than %d bytes, truncated\n", cmd, name, line, max)
+}
+
+// eachInput calls fn with each of the named files in turn, or with stdin if
+// no names are given or the name is "-".
+func eachInput(names []string, stdin io.Reader, fn func(name string, r io.Reader) error) error {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	for _, name := range names {
+		if name == "-" {
+			if err := fn(name, stdin); err != nil {
+				return err
+			}
+			continue
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = fn(name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Command zapfmt works with the logs written by the key value encoder.
+//
+// Usage:
+//
+//	zapfmt <command> [flags] [file ...]
+//
+// Commands read the given files one after the other, or standard input if
+// none or "-" is given. Run "zapfmt <command> -h" for the flags of a command.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command is a zapfmt subcommand.
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"convert": {"convert logs between the key value, JSON and logfmt formats", runConvert},
+	"pretty":  {"print logs aligned and colorized for humans", runPretty},
+	"query":   {"print the log lines matching an expression", runQuery},
+	"stats":   {"count entries by key, time and numeric field statistics", runStats},
+	"tail":    {"print and follow the last lines of files, merged by time", runTail},
+	"trace":   {"print the timeline of the entries of a request", runTrace},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command named by the first argument, returning the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "zapfmt: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	if err := cmd.run(args[1:], stdin, stdout, stderr); err != nil {
+		if err != errUsage {
+			fmt.Fprintf(stderr, "zapfmt %s: %v\n", args[0], err)
+		}
+		return exitCode(err)
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: zapfmt <command> [flags] [file ...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
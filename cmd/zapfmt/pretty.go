@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/emiguens/zapfmt/kvparse"
+	"go.uber.org/zap/zapcore"
+)
+
+// now tells the current time, to resolve relative time windows.
+var now = time.Now
+
+// runPretty prints logs for humans: aligned and colorized columns, with
+// nested values and stacktraces spread across lines.
+func runPretty(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pretty", flag.ContinueOnError)
+	from := fs.String("from", "kv", "format of the input: kv, json or logfmt")
+	fields := fs.String("fields", "", "comma separated `keys` of the fields to print, all of them if empty")
+	since := fs.String("since", "", "only print entries at or after `time`, either RFC3339 or a duration before now such as 10m")
+	until := fs.String("until", "", "only print entries before `time`, either RFC3339 or a duration before now such as 10m")
+	color := fs.String("color", "auto", "colorize the output: auto, always or never")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: zapfmt pretty [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	in, err := lookupFormat(*from)
+	if err != nil {
+		return err
+	}
+	win, err := parseWindow(*since, *until)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(stdout)
+	p := &printer{w: w}
+	if p.color, err = colorMode(*color, stdout); err != nil {
+		return err
+	}
+	if *fields != "" {
+		p.fields = make(map[string]bool)
+		for _, k := range strings.Split(*fields, ",") {
+			p.fields[strings.TrimSpace(k)] = true
+		}
+	}
+
+	err = eachInput(fs.Args(), stdin, func(name string, r io.Reader) error {
+		sc := kvparse.NewScanner(r)
+		for sc.Scan() {
+			rec, err := in.parse(sc.Line())
+			if err != nil {
+				// Lines that aren't entries, such as panics, are kept
+				// unless a window is given, as their time is unknown.
+				if win.empty() {
+					w.Write(sc.Line())
+					w.WriteByte('\n')
+				}
+				continue
+			}
+			if !win.empty() {
+				t, ok := rec.Time()
+				if !ok || !win.contains(t) {
+					continue
+				}
+			}
+			p.print(rec)
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("reading %s: %v", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// window is a time range, open on the sides that are zero.
+type window struct {
+	since, until time.Time
+}
+
+func (win window) empty() bool {
+	return win.since.IsZero() && win.until.IsZero()
+}
+
+func (win window) contains(t time.Time) bool {
+	return (win.since.IsZero() || !t.Before(win.since)) && (win.until.IsZero() || t.Before(win.until))
+}
+
+func parseWindow(since, until string) (window, error) {
+	var (
+		win window
+		err error
+	)
+	if since != "" {
+		if win.since, err = parseTime(since); err != nil {
+			return win, fmt.Errorf("invalid -since: %v", err)
+		}
+	}
+	if until != "" {
+		if win.until, err = parseTime(until); err != nil {
+			return win, fmt.Errorf("invalid -until: %v", err)
+		}
+	}
+	return win, nil
+}
+
+// parseTime parses an RFC3339 time, or a duration before now.
+func parseTime(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC3339 time nor a duration", s)
+	}
+	return t, nil
+}
+
+// colorMode reports whether output to w is colorized in the given mode.
+func colorMode(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "auto":
+		return isTerminal(w) && os.Getenv("NO_COLOR") == "", nil
+	case "never":
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown color mode %q, must be one of auto, always, never", mode)
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// ANSI escape sequences.
+const (
+	reset   = "\x1b[0m"
+	bold    = "\x1b[1m"
+	faint   = "\x1b[2m"
+	red     = "\x1b[31m"
+	yellow  = "\x1b[33m"
+	blue    = "\x1b[34m"
+	magenta = "\x1b[35m"
+	cyan    = "\x1b[36m"
+)
+
+func levelColor(lvl zapcore.Level) string {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return magenta
+	case lvl == zapcore.InfoLevel:
+		return blue
+	case lvl == zapcore.WarnLevel:
+		return yellow
+	case lvl == zapcore.ErrorLevel:
+		return red
+	default:
+		return bold + red
+	}
+}
+
+// printer writes records as aligned columns: time, level, logger and caller,
+// followed by the message and the fields. Column widths grow with the widest
+// value seen so far, as the input is streamed.
+type printer struct {
+	w      *bufio.Writer
+	color  bool
+	fields map[string]bool
+
+	loggerWidth int
+	callerWidth int
+}
+
+func (p *printer) selected(key string) bool {
+	return p.fields == nil || p.fields[key]
+}
+
+func (p *printer) print(rec kvparse.Record) {
+	var blocks []kvparse.Field
+	first := true
+	sep := func() {
+		if !first {
+			p.w.WriteByte(' ')
+		}
+		first = false
+	}
+
+	if v, ok := rec.Get("ts"); ok && p.selected("ts") {
+		sep()
+		p.paint(faint, v.Str)
+	}
+	if v, ok := rec.Get("level"); ok && p.selected("level") {
+		sep()
+		text := fmt.Sprintf("%-5s", strings.ToUpper(v.Str))
+		if lvl, ok := rec.Level(); ok {
+			p.paint(levelColor(lvl), text)
+		} else {
+			p.w.WriteString(escape(text))
+		}
+	}
+	if p.selected("logger") {
+		v, ok := rec.Get("logger")
+		if ok || p.loggerWidth > 0 {
+			sep()
+			p.loggerWidth = p.pad(cyan, v.Str, p.loggerWidth)
+		}
+	}
+	if p.selected("caller") {
+		v, ok := rec.Get("caller")
+		if ok || p.callerWidth > 0 {
+			sep()
+			p.callerWidth = p.pad(faint, v.Str, p.callerWidth)
+		}
+	}
+	if v, ok := rec.Get("msg"); ok && p.selected("msg") {
+		sep()
+		p.paint(bold, v.Str)
+	}
+
+	for _, f := range rec.Fields {
+		switch f.Key {
+		case "ts", "level", "logger", "caller", "msg":
+			continue
+		}
+		if !p.selected(f.Key) {
+			continue
+		}
+		if multiline(f.Value) {
+			blocks = append(blocks, f)
+			continue
+		}
+		sep()
+		p.paint(cyan, f.Key+"=")
+		p.w.WriteString(inline(f.Value))
+	}
+	p.w.WriteByte('\n')
+
+	for _, f := range blocks {
+		p.block(f, 1)
+	}
+}
+
+// pad writes s padded to the given width, returning the width to use next.
+// Entries missing the column get it blank, so that the next ones stay
+// aligned.
+func (p *printer) pad(color, s string, width int) int {
+	s = escape(s)
+	if len(s) > width {
+		width = len(s)
+	}
+	p.paint(color, s)
+	p.w.WriteString(strings.Repeat(" ", width-len(s)))
+	return width
+}
+
+// paint writes s in the given color, with its control characters escaped.
+func (p *printer) paint(color, s string) {
+	p.w.WriteString(colored(p.color, color, escape(s)))
+}
+
+// colored returns s wrapped in the given color, if on.
+func colored(on bool, color, s string) string {
+	if !on || s == "" {
+		return s
+	}
+	return color + s + reset
+}
+
+// block writes a field over the lines following the entry, indented by the
+// given depth.
+func (p *printer) block(f kvparse.Field, depth int) {
+	indent := strings.Repeat("    ", depth)
+	p.w.WriteString(indent)
+	p.paint(cyan, f.Key+":")
+
+	v := f.Value
+	if !multiline(v) {
+		p.w.WriteByte(' ')
+		p.w.WriteString(inline(v))
+		p.w.WriteByte('\n')
+		return
+	}
+
+	switch v.Kind {
+	case kvparse.ObjectKind:
+		p.w.WriteByte('\n')
+		for _, nested := range v.Fields {
+			p.block(nested, depth+1)
+		}
+	case kvparse.ArrayKind:
+		p.w.WriteByte('\n')
+		for i, e := range v.Elems {
+			p.block(kvparse.Field{Key: "[" + strconv.Itoa(i) + "]", Value: e}, depth+1)
+		}
+	default:
+		p.w.WriteByte('\n')
+		for _, line := range strings.Split(strings.TrimRight(v.Str, "\n"), "\n") {
+			p.w.WriteString(indent)
+			p.w.WriteString("    ")
+			p.w.WriteString(escape(line))
+			p.w.WriteByte('\n')
+		}
+	}
+}
+
+// multiline reports whether a value is written over several lines: objects,
+// arrays holding objects or arrays, and strings with line breaks, such as
+// stacktraces.
+func multiline(v kvparse.Value) bool {
+	switch v.Kind {
+	case kvparse.ObjectKind:
+		return len(v.Fields) > 0
+	case kvparse.ArrayKind:
+		for _, e := range v.Elems {
+			if e.Kind == kvparse.ObjectKind || e.Kind == kvparse.ArrayKind {
+				return true
+			}
+		}
+	case kvparse.StringKind:
+		return strings.Contains(v.Str, "\n")
+	}
+	return false
+}
+
+// inline returns the text of a value written on the entry line, quoting
+// strings that would be ambiguous or that hold control characters.
+func inline(v kvparse.Value) string {
+	switch v.Kind {
+	case kvparse.StringKind:
+		if v.Str == "" || strings.ContainsAny(v.Str, " \t\"=") || strings.IndexFunc(v.Str, unicode.IsControl) >= 0 {
+			return strconv.Quote(v.Str)
+		}
+		return v.Str
+	case kvparse.ArrayKind:
+		elems := make([]string, len(v.Elems))
+		for i, e := range v.Elems {
+			elems[i] = inline(e)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case kvparse.ObjectKind:
+		return "{}"
+	case kvparse.NullKind:
+		return "null"
+	default:
+		return escape(v.Str)
+	}
+}
+
+// escape returns s with its control characters, C0 and C1 alike but tabs,
+// written as Go escapes, so that logs can't send escape sequences to the
+// terminal, such as ones changing its colors or title.
+func escape(s string) string {
+	if strings.IndexFunc(s, isControl) < 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	for _, r := range s {
+		if !isControl(r) {
+			sb.WriteRune(r)
+			continue
+		}
+		q := strconv.QuoteRune(r)
+		sb.WriteString(q[1 : len(q)-1])
+	}
+	return sb.String()
+}
+
+// isControl reports whether r is a control character other than a tab.
+func isControl(r rune) bool {
+	return r != '\t' && unicode.IsControl(r)
+}
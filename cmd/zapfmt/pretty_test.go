@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPretty(t *testing.T) {
+	const input = "[ts:2019-04-08T20:21:32.000000Z][level:info][logger:http][caller:http/server.go:12][msg:started][port:8080][addr:[a][b]]\n" +
+		"[ts:2019-04-08T20:21:33.000000Z][level:warn][caller:db/conn.go:142][msg:slow query][user:{name:jane doe][roles:[admin]}][elapsed:1.5]\n" +
+		"[ts:2019-04-08T20:21:34.000000Z][level:error][msg:failed][stacktrace:main.main\\n\\t/src/main.go:12]\n" +
+		"panic: something went wrong\n"
+
+	now = func() time.Time { return time.Date(2019, time.April, 8, 20, 21, 35, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	tt := []struct {
+		Name     string
+		Args     []string
+		Expected string
+	}{
+		{
+			Name: "Aligns Columns And Spreads Nested Values",
+			Expected: "2019-04-08T20:21:32.000000Z INFO  http http/server.go:12 started port=8080 addr=[a, b]\n" +
+				"2019-04-08T20:21:33.000000Z WARN       db/conn.go:142    slow query elapsed=1.5\n" +
+				"    user:\n" +
+				"        name: \"jane doe\"\n" +
+				"        roles: [admin]\n" +
+				"2019-04-08T20:21:34.000000Z ERROR                        failed\n" +
+				"    stacktrace:\n" +
+				"        main.main\n" +
+				"        \t/src/main.go:12\n" +
+				"panic: something went wrong\n",
+		},
+		{
+			Name: "Selected Fields",
+			Args: []string{"-fields", "level, msg,elapsed"},
+			Expected: "INFO  started\n" +
+				"WARN  slow query elapsed=1.5\n" +
+				"ERROR failed\n" +
+				"panic: something went wrong\n",
+		},
+		{
+			Name:     "Time Window",
+			Args:     []string{"-fields", "msg", "-since", "2019-04-08T20:21:33Z", "-until", "1s"},
+			Expected: "slow query\n",
+		},
+		{
+			Name: "Colors",
+			Args: []string{"-fields", "level,msg,port", "-color", "always"},
+			Expected: "\x1b[34mINFO \x1b[0m \x1b[1mstarted\x1b[0m \x1b[36mport=\x1b[0m8080\n" +
+				"\x1b[33mWARN \x1b[0m \x1b[1mslow query\x1b[0m\n" +
+				"\x1b[31mERROR\x1b[0m \x1b[1mfailed\x1b[0m\n" +
+				"panic: something went wrong\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"pretty"}, tc.Args...), strings.NewReader(input), &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tc.Expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.Expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestPrettyControlCharacters(t *testing.T) {
+	const input = "[level:info][logger:http\\u001b[2J][msg:title\\u001b]0;pwned\\u0007 set][user:jane\u009b31m][trace:main.main\\n\\t\\u001b[31m/src/main.go:12]\n"
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"pretty", "-color", "always"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+	}
+	expected := "\x1b[34mINFO \x1b[0m \x1b[36mhttp\\x1b[2J\x1b[0m \x1b[1mtitle\\x1b]0;pwned\\a set\x1b[0m \x1b[36muser=\x1b[0m\"jane\\u009b31m\"\n" +
+		"    \x1b[36mtrace:\x1b[0m\n" +
+		"        main.main\n" +
+		"        \t\\x1b[31m/src/main.go:12\n"
+	if stdout.String() != expected {
+		t.Fatalf("expected:\n%q\ngot:\n%q", expected, stdout.String())
+	}
+}
+
+func TestPrettyErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"pretty", "-since", "yesterday"},
+		{"pretty", "-color", "rainbow"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader(""), &stdout, &stderr); code != 1 {
+			t.Errorf("expected %v to fail, got exit code %d", args, code)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+// runQuery prints the lines matching a filter expression, see parseFilter.
+func runQuery(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	from := fs.String("from", "kv", "format of the input: kv, json or logfmt")
+	to := fs.String("to", "", "format of the output: kv, json or logfmt, the lines as read if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: zapfmt query [flags] <expression> [file ...]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, `example: zapfmt query 'level>=warn && request_id=="abc" && duration>0.5' app.log`)
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	match, err := parseFilter(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid expression: %v", err)
+	}
+	in, err := lookupFormat(*from)
+	if err != nil {
+		return err
+	}
+	var out format
+	if *to != "" {
+		if out, err = lookupFormat(*to); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(stdout)
+	var buf []byte
+	err = eachInput(fs.Args()[1:], stdin, func(name string, r io.Reader) error {
+		sc := kvparse.NewScanner(r)
+		for sc.Scan() {
+			rec, err := in.parse(sc.Line())
+			if err != nil || !match(rec) {
+				continue
+			}
+
+			buf = buf[:0]
+			if out.append != nil {
+				buf = out.append(buf, rec)
+			} else {
+				buf = append(buf, sc.Line()...)
+			}
+			buf = append(buf, '\n')
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("reading %s: %v", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
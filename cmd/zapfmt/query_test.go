@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	const input = "[level:info][msg:started][port:8080]\n" +
+		"[level:warn][msg:slow query][request_id:abc][duration:0.75]\n" +
+		"panic: something went wrong\n" +
+		"[level:error][msg:failed][request_id:abc][db:{table:users}]\n" +
+		"[level:error][msg:failed][request_id:def]\n"
+
+	tt := []struct {
+		Name     string
+		Args     []string
+		Expected string
+	}{
+		{
+			Name:     "Original Lines",
+			Args:     []string{`level>=warn && request_id=="abc"`},
+			Expected: "[level:warn][msg:slow query][request_id:abc][duration:0.75]\n[level:error][msg:failed][request_id:abc][db:{table:users}]\n",
+		},
+		{
+			Name:     "Converted Lines",
+			Args:     []string{"-to", "json", `db.table==users`},
+			Expected: `{"level":"error","msg":"failed","request_id":"abc","db":{"table":"users"}}` + "\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"query"}, tc.Args...), strings.NewReader(input), &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tc.Expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.Expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Args []string
+		Code int
+	}{
+		{[]string{"query"}, 2},
+		{[]string{"query", "level>="}, 1},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(tc.Args, strings.NewReader(""), &stdout, &stderr); code != tc.Code {
+			t.Errorf("%v: expected exit code %d, got %d", tc.Args, tc.Code, code)
+		}
+	}
+}
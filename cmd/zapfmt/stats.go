@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+// stats are the statistics of a numeric field, in the order they're printed.
+var stats = []string{"min", "mean", "p50", "p90", "p99", "max"}
+
+// runStats aggregates entries: counts grouped by the values of some keys,
+// optionally in time buckets, with the percentiles of a numeric field, or the
+// histogram of that field.
+func runStats(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	from := fs.String("from", "kv", "format of the input: kv, json or logfmt")
+	where := fs.String("where", "", "only count entries matching the `expression`, as given to query")
+	by := fs.String("by", "msg", "comma separated `keys` to group entries by, none if empty")
+	field := fs.String("field", "", "numeric `key` to compute the statistics of, such as duration")
+	bucket := fs.Duration("bucket", 0, "group entries in time buckets of the given `duration` too, such as 1m")
+	sortBy := fs.String("sort", "count", "sort groups by count or by a statistic of -field: "+strings.Join(stats, ", "))
+	top := fs.Int("top", 10, "print the first `n` groups, all of them if zero; ignored with -bucket, which sorts by time")
+	histogram := fs.Int("histogram", 0, "print a histogram of -field with `n` bins instead of groups")
+	output := fs.String("output", "table", "output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: zapfmt stats [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	in, err := lookupFormat(*from)
+	if err != nil {
+		return err
+	}
+	match := func(kvparse.Record) bool { return true }
+	if *where != "" {
+		if match, err = parseFilter(*where); err != nil {
+			return fmt.Errorf("invalid expression: %v", err)
+		}
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("unknown output format %q, must be one of table, json", *output)
+	}
+	if *sortBy != "count" && (*field == "" || statIndex(*sortBy) < 0) {
+		return fmt.Errorf("invalid -sort %q, must be count or a statistic of -field", *sortBy)
+	}
+	if *histogram > 0 && *field == "" {
+		return fmt.Errorf("-histogram needs -field")
+	}
+
+	agg := &aggregator{field: *field, bucket: *bucket, groups: make(map[string]*group)}
+	if *by != "" && *histogram == 0 {
+		for _, k := range strings.Split(*by, ",") {
+			agg.keys = append(agg.keys, strings.TrimSpace(k))
+		}
+	}
+
+	err = eachInput(fs.Args(), stdin, func(name string, r io.Reader) error {
+		sc := kvparse.NewScanner(r)
+		for sc.Scan() {
+			rec, err := in.parse(sc.Line())
+			if err != nil || !match(rec) {
+				continue
+			}
+			agg.add(rec)
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("reading %s: %v", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(stdout)
+	var t *table
+	if *histogram > 0 {
+		t = agg.histogram(*histogram)
+	} else {
+		t = agg.table(*sortBy, *top)
+	}
+	if *output == "json" {
+		err = t.writeJSON(w)
+	} else {
+		t.writeText(w)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func statIndex(name string) int {
+	for i, s := range stats {
+		if s == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// group holds the entries sharing the values of the grouping keys and time
+// bucket.
+type group struct {
+	values []string
+	bucket time.Time
+	count  int
+
+	// samples holds the values of the numeric field, and summary their
+	// statistics once the groups are tabled, nil if there are none.
+	samples []float64
+	summary []float64
+}
+
+// aggregator groups entries.
+type aggregator struct {
+	keys   []string
+	field  string
+	bucket time.Duration
+
+	groups map[string]*group
+	// all holds the samples of every entry, for histograms.
+	all []float64
+}
+
+func (a *aggregator) add(rec kvparse.Record) {
+	var sb strings.Builder
+	var bucket time.Time
+	if a.bucket > 0 {
+		t, ok := rec.Time()
+		if !ok {
+			return
+		}
+		bucket = t.Truncate(a.bucket)
+		sb.WriteString(bucket.String())
+	}
+
+	values := make([]string, len(a.keys))
+	for i, k := range a.keys {
+		if v, ok := rec.Get(k); ok {
+			values[i] = v.String()
+		}
+		sb.WriteByte(0)
+		sb.WriteString(values[i])
+	}
+
+	g, ok := a.groups[sb.String()]
+	if !ok {
+		g = &group{values: values, bucket: bucket}
+		a.groups[sb.String()] = g
+	}
+	g.count++
+
+	if a.field == "" {
+		return
+	}
+	// Infinite values would leave no room for the others in the statistics
+	// and histogram bins, so they're dropped like NaN.
+	if v, ok := rec.Get(a.field); ok {
+		if f, ok := v.Float(); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			g.samples = append(g.samples, f)
+			a.all = append(a.all, f)
+		}
+	}
+}
+
+// table returns the groups sorted by count or by a statistic of the field,
+// or by time if bucketed.
+func (a *aggregator) table(sortBy string, top int) *table {
+	groups := make([]*group, 0, len(a.groups))
+	for _, g := range a.groups {
+		sort.Float64s(g.samples)
+		g.summary = statistics(g.samples)
+		groups = append(groups, g)
+	}
+
+	stat := statIndex(sortBy)
+	sort.Slice(groups, func(i, j int) bool {
+		gi, gj := groups[i], groups[j]
+		if !gi.bucket.Equal(gj.bucket) {
+			return gi.bucket.Before(gj.bucket)
+		}
+		if stat >= 0 {
+			si, sj := gi.summary, gj.summary
+			if (si == nil) != (sj == nil) || si != nil && si[stat] != sj[stat] {
+				return si != nil && (sj == nil || si[stat] > sj[stat])
+			}
+		} else if gi.count != gj.count {
+			return gi.count > gj.count
+		}
+		return strings.Join(gi.values, "\x00") < strings.Join(gj.values, "\x00")
+	})
+	if a.bucket == 0 && top > 0 && len(groups) > top {
+		groups = groups[:top]
+	}
+
+	t := &table{}
+	if a.bucket > 0 {
+		t.columns = append(t.columns, "time")
+	}
+	t.columns = append(t.columns, a.keys...)
+	t.columns = append(t.columns, "count")
+	if a.bucket > 0 {
+		t.columns = append(t.columns, "rate")
+	}
+	if a.field != "" {
+		t.columns = append(t.columns, stats...)
+	}
+
+	for _, g := range groups {
+		var row []cell
+		if a.bucket > 0 {
+			row = append(row, cell{text: g.bucket.UTC().Format(time.RFC3339)})
+		}
+		for _, v := range g.values {
+			row = append(row, cell{text: v})
+		}
+		row = append(row, number(float64(g.count)))
+		if a.bucket > 0 {
+			row = append(row, number(float64(g.count)/a.bucket.Seconds()))
+		}
+		if a.field != "" {
+			for i := range stats {
+				if g.summary != nil {
+					row = append(row, number(g.summary[i]))
+				} else {
+					row = append(row, cell{})
+				}
+			}
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+// statistics returns the statistics of the sorted samples, in the order of
+// stats, or nil if there are none.
+func statistics(samples []float64) []float64 {
+	n := len(samples)
+	if n == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	return []float64{
+		samples[0],
+		sum / float64(n),
+		percentile(samples, 50),
+		percentile(samples, 90),
+		percentile(samples, 99),
+		samples[n-1],
+	}
+}
+
+// percentile returns the nearest rank percentile of the sorted samples.
+func percentile(samples []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(samples))))
+	if rank < 1 {
+		rank = 1
+	}
+	return samples[rank-1]
+}
+
+// histogram returns the histogram of the field values, in bins of equal
+// width between the smallest and largest values.
+func (a *aggregator) histogram(bins int) *table {
+	t := &table{columns: []string{"from", "to", "count", ""}}
+	if len(a.all) == 0 {
+		return t
+	}
+
+	sort.Float64s(a.all)
+	lo, hi := a.all[0], a.all[len(a.all)-1]
+	// Dividing first keeps the width finite for values far apart, whose
+	// difference would overflow.
+	width := hi/float64(bins) - lo/float64(bins)
+	if width == 0 {
+		bins, width = 1, 1
+	}
+
+	counts := make([]int, bins)
+	max := 0
+	for _, v := range a.all {
+		i := int(v/width - lo/width)
+		if i < 0 {
+			i = 0
+		}
+		if i >= bins {
+			i = bins - 1
+		}
+		counts[i]++
+		if counts[i] > max {
+			max = counts[i]
+		}
+	}
+
+	const barWidth = 40
+	for i, c := range counts {
+		t.rows = append(t.rows, []cell{
+			number(lo + float64(i)*width),
+			number(math.Min(lo+float64(i+1)*width, hi)),
+			number(float64(c)),
+			{text: strings.Repeat("#", (c*barWidth+max-1)/max)},
+		})
+	}
+	return t
+}
+
+// cell is a value of a table, a number unless text is set.
+type cell struct {
+	text  string
+	num   float64
+	isNum bool
+}
+
+func number(f float64) cell {
+	return cell{num: f, isNum: true}
+}
+
+// value returns the cell as a value, with numbers JSON can't hold, such as
+// a mean overflowing to infinity, as strings.
+func (c cell) value() kvparse.Value {
+	switch {
+	case c.isNum && (math.IsInf(c.num, 0) || math.IsNaN(c.num)):
+		return kvparse.Value{Str: strconv.FormatFloat(c.num, 'g', -1, 64)}
+	case c.isNum:
+		return kvparse.Value{Kind: kvparse.NumberKind, Str: strconv.FormatFloat(c.num, 'g', -1, 64)}
+	case c.text == "":
+		return kvparse.Value{Kind: kvparse.NullKind}
+	}
+	return kvparse.Value{Str: c.text}
+}
+
+func (c cell) String() string {
+	if c.isNum {
+		return strconv.FormatFloat(c.num, 'g', 6, 64)
+	}
+	if c.text == "" {
+		return "-"
+	}
+	return c.text
+}
+
+// table is the output of the stats command.
+type table struct {
+	columns []string
+	rows    [][]cell
+}
+
+func (t *table) writeText(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, c := range t.columns {
+		if i > 0 {
+			tw.Write([]byte{'\t'})
+		}
+		tw.Write([]byte(strings.ToUpper(c)))
+	}
+	tw.Write([]byte{'\n'})
+
+	for _, row := range t.rows {
+		for i, c := range row {
+			if i > 0 {
+				tw.Write([]byte{'\t'})
+			}
+			tw.Write([]byte(c.String()))
+		}
+		tw.Write([]byte{'\n'})
+	}
+	tw.Flush()
+}
+
+// writeJSON writes the rows as an array of JSON objects keyed by column, with
+// null for missing values. Unnamed columns are left out.
+func (t *table) writeJSON(w io.Writer) error {
+	buf := []byte("[")
+	for i, row := range t.rows {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, "\n  "...)
+
+		var rec kvparse.Record
+		for j, c := range row {
+			if t.columns[j] != "" {
+				rec.Fields = append(rec.Fields, kvparse.Field{Key: t.columns[j], Value: c.value()})
+			}
+		}
+		buf = kvparse.AppendJSON(buf, rec)
+	}
+	buf = append(buf, "\n]\n"...)
+
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	const input = "[ts:2019-04-08T20:21:02Z][level:info][msg:request][route:/users][duration:0.1]\n" +
+		"[ts:2019-04-08T20:21:12Z][level:info][msg:request][route:/users][duration:0.3]\n" +
+		"[ts:2019-04-08T20:21:22Z][level:error][msg:failed][route:/orders]\n" +
+		"[ts:2019-04-08T20:22:02Z][level:info][msg:request][route:/orders][duration:2s]\n" +
+		"[ts:2019-04-08T20:22:12Z][level:error][msg:failed][route:/users]\n" +
+		"[ts:2019-04-08T20:23:02Z][level:error][msg:failed][route:/users]\n" +
+		"[ts:2019-04-08T20:23:12Z][level:info][msg:request][route:/users][duration:0.2]\n" +
+		"panic: something went wrong\n"
+
+	tt := []struct {
+		Name     string
+		Args     []string
+		Expected string
+	}{
+		{
+			Name: "Top Messages",
+			Expected: "MSG      COUNT\n" +
+				"request  4\n" +
+				"failed   3\n",
+		},
+		{
+			Name: "Slowest Routes",
+			Args: []string{"-by", "route", "-field", "duration", "-sort", "p99", "-where", "msg==request"},
+			Expected: "ROUTE    COUNT  MIN  MEAN  P50  P90  P99  MAX\n" +
+				"/orders  1      2    2     2    2    2    2\n" +
+				"/users   3      0.1  0.2   0.2  0.3  0.3  0.3\n",
+		},
+		{
+			Name: "Error Rate Per Minute",
+			Args: []string{"-by", "", "-where", "level>=error", "-bucket", "1m"},
+			Expected: "TIME                  COUNT  RATE\n" +
+				"2019-04-08T20:21:00Z  1      0.0166667\n" +
+				"2019-04-08T20:22:00Z  1      0.0166667\n" +
+				"2019-04-08T20:23:00Z  1      0.0166667\n",
+		},
+		{
+			Name: "Missing Values",
+			Args: []string{"-by", "route,level", "-field", "duration", "-top", "2"},
+			Expected: "ROUTE   LEVEL  COUNT  MIN  MEAN  P50  P90  P99  MAX\n" +
+				"/users  info   3      0.1  0.2   0.2  0.3  0.3  0.3\n" +
+				"/users  error  2      -    -     -    -    -    -\n",
+		},
+		{
+			Name: "Histogram",
+			Args: []string{"-field", "duration", "-histogram", "2"},
+			Expected: "FROM  TO    COUNT  \n" +
+				"0.1   1.05  3      ########################################\n" +
+				"1.05  2     1      ##############\n",
+		},
+		{
+			Name: "JSON",
+			Args: []string{"-by", "route", "-field", "duration", "-top", "1", "-output", "json", "-where", "level==error"},
+			Expected: "[\n" +
+				`  {"route":"/users","count":2,"min":null,"mean":null,"p50":null,"p90":null,"p99":null,"max":null}` + "\n" +
+				"]\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"stats"}, tc.Args...), strings.NewReader(input), &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tc.Expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.Expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestStatsNonFinite(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Args     []string
+		Input    string
+		Expected string
+	}{
+		{
+			Name:  "Histogram Drops Infinite Values",
+			Args:  []string{"-field", "duration", "-histogram", "4"},
+			Input: "[duration:1]\n[duration:2]\n[duration:+Inf]\n[duration:-Inf]\n",
+			Expected: "FROM  TO    COUNT  \n" +
+				"1     1.25  1      ########################################\n" +
+				"1.25  1.5   0      -\n" +
+				"1.5   1.75  0      -\n" +
+				"1.75  2     1      ########################################\n",
+		},
+		{
+			Name:  "Histogram Of Extreme Values",
+			Args:  []string{"-field", "duration", "-histogram", "2"},
+			Input: "[duration:-1e308]\n[duration:1e308]\n",
+			Expected: "FROM     TO      COUNT  \n" +
+				"-1e+308  0       1      ########################################\n" +
+				"0        1e+308  1      ########################################\n",
+		},
+		{
+			Name:     "Overflowing Mean As JSON",
+			Args:     []string{"-by", "", "-field", "duration", "-output", "json"},
+			Input:    "[duration:1e308]\n[duration:1e308]\n",
+			Expected: "[\n" + `  {"count":2,"min":1e+308,"mean":"+Inf","p50":1e+308,"p90":1e+308,"p99":1e+308,"max":1e+308}` + "\n]\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"stats"}, tc.Args...), strings.NewReader(tc.Input), &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tc.Expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.Expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestStatsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"stats", "-sort", "p99"},
+		{"stats", "-field", "duration", "-sort", "p42"},
+		{"stats", "-histogram", "10"},
+		{"stats", "-output", "xml"},
+		{"stats", "-where", "level>="},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader(""), &stdout, &stderr); code != 1 {
+			t.Errorf("expected %v to fail, got exit code %d", args, code)
+		}
+	}
+}
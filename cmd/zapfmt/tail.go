@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+// runTail prints the last lines of files merged by time, and optionally
+// follows them as they grow.
+func runTail(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "follow the files as they grow, across rotations and truncations")
+	lines := fs.Int("n", 10, "print the last `n` lines of each file, all of them if negative")
+	where := fs.String("where", "", "only print entries matching the `expression`, as given to query")
+	from := fs.String("from", "kv", "format of the input: kv, json or logfmt")
+	to := fs.String("to", "", "format of the output: kv, json or logfmt, the lines as read if empty")
+	prefix := fs.Bool("prefix", true, "prefix lines with the name of their file")
+	delay := fs.Duration("delay", time.Second, "how long lines wait for the lines of other files, so that they're merged in order")
+	interval := fs.Duration("interval", 250*time.Millisecond, "how often files are checked for new lines")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: zapfmt tail [flags] file ...")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	t := &tailer{
+		w:      bufio.NewWriter(stdout),
+		prefix: *prefix,
+		follow: *follow,
+		delay:  *delay,
+	}
+	var err error
+	if t.in, err = lookupFormat(*from); err != nil {
+		return err
+	}
+	if *to != "" {
+		if t.out, err = lookupFormat(*to); err != nil {
+			return err
+		}
+	}
+	if *where != "" {
+		if t.match, err = parseFilter(*where); err != nil {
+			return fmt.Errorf("invalid expression: %v", err)
+		}
+	}
+
+	if err := t.open(fs.Args(), *lines); err != nil {
+		return err
+	}
+	defer t.close()
+
+	if !t.follow {
+		if err := t.emit(now()); err != nil {
+			return err
+		}
+		return t.w.Flush()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if err := t.emit(now()); err != nil {
+			return err
+		}
+		if err := t.w.Flush(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// tailer merges the lines of several files by time. Every file is expected
+// to be in time order, so only its next line is held while the other files
+// catch up.
+type tailer struct {
+	in, out format
+	match   filter
+	prefix  bool
+	follow  bool
+	delay   time.Duration
+
+	w       *bufio.Writer
+	sources []*source
+	buf     []byte
+}
+
+// tailLine is a line read from a source.
+type tailLine struct {
+	text []byte
+	rec  kvparse.Record
+	ok   bool
+
+	// ts is the time of the entry, or of the entry before it if it has none,
+	// such as the lines of a panic.
+	ts time.Time
+}
+
+// source is a followed file.
+type source struct {
+	name string
+	f    *os.File
+	info os.FileInfo
+	r    *bufio.Reader
+
+	// offset is the number of bytes read from f, partial the line being read
+	// when its end wasn't written yet, and last the time of the last line.
+	offset  int64
+	partial []byte
+	last    time.Time
+
+	// head is the next line to write, and next the file that replaced f once
+	// it's read until its end.
+	head *tailLine
+	next *os.File
+
+	// idle is since when the source has no more lines, zero if it has.
+	idle time.Time
+}
+
+// open opens the files, positioned n lines before their end.
+func (t *tailer) open(names []string, n int) error {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			t.close()
+			return err
+		}
+		s := &source{name: name, f: f}
+		t.sources = append(t.sources, s)
+
+		if s.info, err = f.Stat(); err != nil {
+			t.close()
+			return err
+		}
+		if n >= 0 {
+			if s.offset, err = seekLastLines(f, n); err != nil {
+				t.close()
+				return fmt.Errorf("reading %s: %v", name, err)
+			}
+		}
+		s.r = bufio.NewReader(f)
+	}
+	return nil
+}
+
+func (t *tailer) close() {
+	for _, s := range t.sources {
+		s.f.Close()
+		if s.next != nil {
+			s.next.Close()
+		}
+	}
+}
+
+// emit writes the lines read so far in time order. When following, a line
+// is held while another file without lines may still write an earlier one:
+// until it writes a later one, or stays idle for delay.
+func (t *tailer) emit(now time.Time) error {
+	for {
+		var first *source
+		for _, s := range t.sources {
+			if s.head == nil {
+				l, err := s.read(t.in, t.follow)
+				if err != nil {
+					return fmt.Errorf("reading %s: %v", s.name, err)
+				}
+				s.head = l
+			}
+			if s.head == nil {
+				if s.idle.IsZero() {
+					s.idle = now
+				}
+				continue
+			}
+			s.idle = time.Time{}
+			if first == nil || s.head.ts.Before(first.head.ts) {
+				first = s
+			}
+		}
+		if first == nil {
+			return nil
+		}
+
+		if t.follow {
+			for _, s := range t.sources {
+				if s.head == nil && s.last.Before(first.head.ts) && now.Sub(s.idle) < t.delay {
+					return nil
+				}
+			}
+		}
+
+		if err := t.write(first); err != nil {
+			return err
+		}
+		first.head = nil
+	}
+}
+
+func (t *tailer) write(s *source) error {
+	l := s.head
+	if t.match != nil && (!l.ok || !t.match(l.rec)) {
+		return nil
+	}
+
+	t.buf = t.buf[:0]
+	if t.prefix {
+		t.buf = append(t.buf, s.name...)
+		t.buf = append(t.buf, ": "...)
+	}
+	if t.out.append != nil && l.ok {
+		t.buf = t.out.append(t.buf, l.rec)
+	} else {
+		t.buf = append(t.buf, l.text...)
+	}
+	t.buf = append(t.buf, '\n')
+	_, err := t.w.Write(t.buf)
+	return err
+}
+
+// read returns the next complete line of the source, or nil if there's none
+// yet. When following, the end of the file is when rotations and truncations
+// are noticed.
+func (s *source) read(in format, follow bool) (*tailLine, error) {
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		s.offset += int64(len(chunk))
+		if room := kvparse.DefaultMaxLineSize - len(s.partial); len(chunk) > room {
+			chunk = chunk[:room]
+		}
+		s.partial = append(s.partial, chunk...)
+
+		switch err {
+		case nil:
+			return s.line(in), nil
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+		default:
+			return nil, err
+		}
+
+		if !follow {
+			if len(s.partial) > 0 {
+				return s.line(in), nil
+			}
+			return nil, nil
+		}
+		if changed, err := s.reopen(); err != nil || !changed {
+			return nil, err
+		}
+	}
+}
+
+// line returns the line held in partial.
+func (s *source) line(in format) *tailLine {
+	l := &tailLine{
+		text: append([]byte(nil), bytes.TrimRight(s.partial, "\r\n")...),
+		ts:   s.last,
+	}
+	s.partial = s.partial[:0]
+
+	if rec, err := in.parse(l.text); err == nil {
+		l.rec, l.ok = rec, true
+		if ts, ok := rec.Time(); ok {
+			l.ts = ts
+		}
+	}
+	s.last = l.ts
+	return l
+}
+
+// reopen switches to the file that replaced the one read if it was rotated,
+// and rewinds it if it was truncated, reporting whether there may be more to
+// read. A rotated file is read once more until its end before switching, in
+// case it was written to in between.
+func (s *source) reopen() (bool, error) {
+	if s.next != nil {
+		s.f.Close()
+		s.f, s.next = s.next, nil
+		if info, err := s.f.Stat(); err == nil {
+			s.info = info
+		}
+		s.offset = 0
+		s.partial = s.partial[:0]
+		s.r.Reset(s.f)
+		return true, nil
+	}
+
+	info, err := os.Stat(s.name)
+	if errors.Is(err, os.ErrNotExist) {
+		// The file was moved away and not replaced yet.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(info, s.info) {
+		if s.next, err = os.Open(s.name); err != nil {
+			return false, nil
+		}
+		return true, nil
+	}
+	if info.Size() < s.offset {
+		if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		s.offset = 0
+		s.partial = s.partial[:0]
+		s.r.Reset(s.f)
+		return true, nil
+	}
+	return false, nil
+}
+
+// seekLastLines seeks f to the start of its last n lines, reading it
+// backwards so that large files aren't read whole, and returns the offset.
+func seekLastLines(f *os.File, n int) (int64, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil || n == 0 {
+		return end, err
+	}
+
+	buf := make([]byte, 32*1024)
+	pos, found := end, 0
+	for pos > 0 {
+		size := int64(len(buf))
+		if pos < size {
+			size = pos
+		}
+		pos -= size
+		if _, err := f.ReadAt(buf[:size], pos); err != nil {
+			return 0, err
+		}
+
+		for i := size - 1; i >= 0; i-- {
+			if buf[i] != '\n' || pos+i == end-1 {
+				continue
+			}
+			if found++; found == n {
+				_, err := f.Seek(pos+i+1, io.SeekStart)
+				return pos + i + 1, err
+			}
+		}
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	return 0, err
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendFile(t *testing.T, name, content string) {
+	t.Helper()
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTail(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	writeFile(t, a, "[ts:2019-04-08T20:21:31Z][level:info][msg:a0]\n"+
+		"[ts:2019-04-08T20:21:32Z][level:info][msg:a1]\n"+
+		"[ts:2019-04-08T20:21:34Z][level:error][msg:a2]\n"+
+		"panic: a2\n"+
+		"[ts:2019-04-08T20:21:36Z][level:info][msg:a3]\n")
+	writeFile(t, b, "[ts:2019-04-08T20:21:33Z][level:warn][msg:b1]\n"+
+		"[ts:2019-04-08T20:21:35Z][level:info][msg:b2]\n"+
+		"[ts:2019-04-08T20:21:37Z][level:error][msg:b3]")
+
+	tt := []struct {
+		Name     string
+		Args     []string
+		Expected string
+	}{
+		{
+			Name: "Merges By Time",
+			Args: []string{"-n", "-1", a, b},
+			Expected: a + ": [ts:2019-04-08T20:21:31Z][level:info][msg:a0]\n" +
+				a + ": [ts:2019-04-08T20:21:32Z][level:info][msg:a1]\n" +
+				b + ": [ts:2019-04-08T20:21:33Z][level:warn][msg:b1]\n" +
+				a + ": [ts:2019-04-08T20:21:34Z][level:error][msg:a2]\n" +
+				a + ": panic: a2\n" +
+				b + ": [ts:2019-04-08T20:21:35Z][level:info][msg:b2]\n" +
+				a + ": [ts:2019-04-08T20:21:36Z][level:info][msg:a3]\n" +
+				b + ": [ts:2019-04-08T20:21:37Z][level:error][msg:b3]\n",
+		},
+		{
+			Name: "Last Lines",
+			Args: []string{"-n", "2", "-prefix=false", a, b},
+			Expected: "panic: a2\n" +
+				"[ts:2019-04-08T20:21:35Z][level:info][msg:b2]\n" +
+				"[ts:2019-04-08T20:21:36Z][level:info][msg:a3]\n" +
+				"[ts:2019-04-08T20:21:37Z][level:error][msg:b3]\n",
+		},
+		{
+			Name: "Filtered And Converted",
+			Args: []string{"-n", "-1", "-prefix=false", "-where", "level>=warn", "-to", "logfmt", a, b},
+			Expected: "ts=2019-04-08T20:21:33Z level=warn msg=b1\n" +
+				"ts=2019-04-08T20:21:34Z level=error msg=a2\n" +
+				"ts=2019-04-08T20:21:37Z level=error msg=b3\n",
+		},
+		{
+			Name:     "No Lines",
+			Args:     []string{"-n", "0", a, b},
+			Expected: "",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"tail"}, tc.Args...), nil, &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tc.Expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.Expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestTailFollow(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	writeFile(t, a, "[ts:2019-04-08T20:21:30Z][msg:a0]\n")
+	writeFile(t, b, "")
+
+	var out bytes.Buffer
+	tl := &tailer{w: bufio.NewWriter(&out), follow: true, delay: time.Second}
+	tl.in, _ = lookupFormat("kv")
+	if err := tl.open([]string{a, b}, 10); err != nil {
+		t.Fatal(err)
+	}
+	defer tl.close()
+
+	start := time.Now()
+	step := func(elapsed time.Duration, expected string) {
+		t.Helper()
+		out.Reset()
+		if err := tl.emit(start.Add(elapsed)); err != nil {
+			t.Fatal(err)
+		}
+		tl.w.Flush()
+		if out.String() != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, out.String())
+		}
+	}
+
+	// Lines wait for the files without lines, until the delay passes.
+	step(0, "")
+	step(time.Second, "[ts:2019-04-08T20:21:30Z][msg:a0]\n")
+
+	// Later lines wait for earlier lines of the other files.
+	appendFile(t, a, "[ts:2019-04-08T20:21:33Z][msg:a1]\n")
+	appendFile(t, b, "[ts:2019-04-08T20:21:32Z][msg:b1]\n[ts:2019-04-08T20:21:34Z][msg:b")
+	step(time.Second, "[ts:2019-04-08T20:21:32Z][msg:b1]\n")
+
+	// Partial lines are completed.
+	appendFile(t, b, "2]\n")
+	step(2*time.Second, "[ts:2019-04-08T20:21:33Z][msg:a1]\n")
+	step(3*time.Second, "[ts:2019-04-08T20:21:34Z][msg:b2]\n")
+
+	// Truncated files are read from their start.
+	writeFile(t, b, "[ts:2019-04-08T20:21:35Z][msg:b3]\n")
+	appendFile(t, a, "[ts:2019-04-08T20:21:36Z][msg:a2]\n")
+	step(3*time.Second, "[ts:2019-04-08T20:21:35Z][msg:b3]\n")
+
+	// Rotated files are read until their end, then replaced.
+	appendFile(t, b, "[ts:2019-04-08T20:21:37Z][msg:b4]\n")
+	if err := os.Rename(b, b+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, b+".1", "[ts:2019-04-08T20:21:38Z][msg:b5]\n")
+	writeFile(t, b, "[ts:2019-04-08T20:21:39Z][msg:b6]\n")
+	step(4*time.Second, "[ts:2019-04-08T20:21:36Z][msg:a2]\n")
+	step(5*time.Second, "[ts:2019-04-08T20:21:37Z][msg:b4]\n"+
+		"[ts:2019-04-08T20:21:38Z][msg:b5]\n"+
+		"[ts:2019-04-08T20:21:39Z][msg:b6]\n")
+}
+
+func TestSeekLastLines(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.log")
+	long := strings.Repeat("x", 40000)
+	writeFile(t, name, "1\n"+long+"\n3\n4")
+
+	for n, expected := range map[int]string{
+		0: "",
+		1: "4",
+		2: "3\n4",
+		3: long + "\n3\n4",
+		4: "1\n" + long + "\n3\n4",
+		9: "1\n" + long + "\n3\n4",
+	} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := seekLastLines(f, n); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		buf.ReadFrom(f)
+		f.Close()
+		if buf.String() != expected {
+			t.Errorf("%d: expected %.20q, got %.20q", n, expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emiguens/zapfmt/kvparse"
+	"go.uber.org/zap/zapcore"
+)
+
+// runTrace prints the timeline of the entries of a request, collected across
+// files.
+func runTrace(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("trace", flag.ContinueOnError)
+	key := fs.String("key", "request_id", "`key` holding the identifier")
+	from := fs.String("from", "kv", "format of the input: kv, json or logfmt")
+	gap := fs.Duration("gap", time.Second, "highlight the time between entries of at least `duration`")
+	output := fs.String("output", "text", "output format: text or json")
+	color := fs.String("color", "auto", "colorize the output: auto, always or never")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: zapfmt trace [flags] <id> [file ...]")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	id := fs.Arg(0)
+
+	in, err := lookupFormat(*from)
+	if err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q, must be one of text, json", *output)
+	}
+	colorize, err := colorMode(*color, stdout)
+	if err != nil {
+		return err
+	}
+
+	var spans []span
+	err = eachInput(fs.Args()[1:], stdin, func(name string, r io.Reader) error {
+		sc := kvparse.NewScanner(r)
+		for n := 1; sc.Scan(); n++ {
+			if sc.Truncated() {
+				warnTruncated(stderr, "trace", name, n, sc)
+			}
+			rec, err := in.parse(sc.Line())
+			if err != nil {
+				continue
+			}
+			if v, ok := rec.Get(*key); !ok || v.String() != id {
+				continue
+			}
+			ts, ok := rec.Time()
+			if !ok {
+				continue
+			}
+			spans = append(spans, span{source: name, ts: ts, rec: rec})
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("reading %s: %v", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(spans) == 0 {
+		return fmt.Errorf("no entries with %s %s", *key, id)
+	}
+
+	tl := newTimeline(spans, *key, *gap)
+	w := bufio.NewWriter(stdout)
+	if *output == "json" {
+		w.Write(tl.appendJSON(nil))
+	} else {
+		tl.writeText(w, colorize)
+	}
+	return w.Flush()
+}
+
+// span is an entry of a timeline.
+type span struct {
+	source string
+	ts     time.Time
+	rec    kvparse.Record
+
+	// offset is the time since the first entry, and delta the time since the
+	// entry before.
+	offset, delta time.Duration
+	gap, err      bool
+}
+
+// timeline is the time ordered entries of a request.
+type timeline struct {
+	key   string
+	spans []span
+
+	sources, errors int
+}
+
+func newTimeline(spans []span, key string, gap time.Duration) *timeline {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].ts.Before(spans[j].ts)
+	})
+
+	tl := &timeline{key: key, spans: spans}
+	sources := make(map[string]bool)
+	for i := range spans {
+		s := &spans[i]
+		s.offset = s.ts.Sub(spans[0].ts)
+		if i > 0 {
+			s.delta = s.ts.Sub(spans[i-1].ts)
+			s.gap = s.delta >= gap
+		}
+		if lvl, ok := s.rec.Level(); ok && lvl >= zapcore.ErrorLevel {
+			s.err = true
+			tl.errors++
+		}
+		sources[s.source] = true
+	}
+	tl.sources = len(sources)
+	return tl
+}
+
+func (tl *timeline) duration() time.Duration {
+	return tl.spans[len(tl.spans)-1].offset
+}
+
+// writeText writes the timeline as a table, preceded by a summary. Gaps are
+// written as lines of their own, and errors are highlighted.
+func (tl *timeline) writeText(w *bufio.Writer, color bool) {
+	id, _ := tl.spans[0].rec.Get(tl.key)
+	fmt.Fprintf(w, "%s %s: %s over %s across %s, %s\n\n",
+		tl.key, id.String(),
+		plural(len(tl.spans), "entry", "entries"),
+		formatDuration(tl.duration()),
+		plural(tl.sources, "file", "files"),
+		plural(tl.errors, "error", "errors"))
+
+	rows := [][]string{{"OFFSET", "DELTA", "LEVEL", "SOURCE", "MESSAGE"}}
+	for _, s := range tl.spans {
+		lvl, _ := s.rec.Get("level")
+		rows = append(rows, []string{
+			"+" + formatDuration(s.offset),
+			"+" + formatDuration(s.delta),
+			strings.ToUpper(lvl.Str),
+			s.source,
+			tl.message(s.rec),
+		})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	for i, row := range rows {
+		var s *span
+		if i > 0 {
+			s = &tl.spans[i-1]
+		}
+		if s != nil && s.gap {
+			w.WriteString(colored(color, yellow, fmt.Sprintf("  %*s ~ %s gap", widths[0], "", formatDuration(s.delta))))
+			w.WriteByte('\n')
+		}
+
+		marker := "  "
+		if s != nil && s.err {
+			marker = "! "
+		}
+		var sb strings.Builder
+		sb.WriteString(marker)
+		for j, cell := range row {
+			if j == len(row)-1 {
+				sb.WriteString(cell)
+				break
+			}
+			sb.WriteString(cell)
+			sb.WriteString(strings.Repeat(" ", widths[j]-len(cell)+2))
+		}
+
+		line := sb.String()
+		switch {
+		case s == nil:
+			line = colored(color, faint, line)
+		case s.err:
+			line = colored(color, red, line)
+		}
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+}
+
+// message returns the message of an entry followed by its fields, except for
+// the header and the identifier.
+func (tl *timeline) message(rec kvparse.Record) string {
+	var sb strings.Builder
+	if v, ok := rec.Get("msg"); ok {
+		sb.WriteString(v.Str)
+	}
+	for _, f := range rec.Fields {
+		switch f.Key {
+		case "ts", "level", "logger", "caller", "msg", "stacktrace", tl.key:
+			continue
+		}
+		sb.WriteString("  ")
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		sb.WriteString(inline(f.Value))
+	}
+	return sb.String()
+}
+
+// appendJSON appends the timeline as a JSON array holding every entry with
+// its offset and delta in seconds.
+func (tl *timeline) appendJSON(dst []byte) []byte {
+	dst = append(dst, '[')
+	for i, s := range tl.spans {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, "\n  "...)
+		dst = kvparse.AppendJSON(dst, kvparse.Record{Fields: []kvparse.Field{
+			{Key: "offset", Value: seconds(s.offset)},
+			{Key: "delta", Value: seconds(s.delta)},
+			{Key: "source", Value: kvparse.Value{Str: s.source}},
+			{Key: "gap", Value: boolean(s.gap)},
+			{Key: "error", Value: boolean(s.err)},
+			{Key: "entry", Value: kvparse.Value{Kind: kvparse.ObjectKind, Fields: s.rec.Fields}},
+		}})
+	}
+	return append(dst, "\n]\n"...)
+}
+
+func seconds(d time.Duration) kvparse.Value {
+	return kvparse.Value{Kind: kvparse.NumberKind, Str: strconv.FormatFloat(d.Seconds(), 'f', -1, 64)}
+}
+
+func boolean(b bool) kvparse.Value {
+	return kvparse.Value{Kind: kvparse.BoolKind, Str: strconv.FormatBool(b)}
+}
+
+// formatDuration formats d rounded to microseconds, as timestamps are.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+func TestTrace(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	writeFile(t, a, "[ts:2019-04-08T20:21:32.000000Z][level:debug][msg:handling request][request_id:abc][url:/greet]\n"+
+		"[ts:2019-04-08T20:21:32.100000Z][level:info][msg:other request][request_id:def]\n"+
+		"[ts:2019-04-08T20:21:33.512000Z][level:error][msg:query failed][request_id:abc][error:timeout]\n"+
+		"panic: something went wrong\n")
+	writeFile(t, b, "[ts:2019-04-08T20:21:32.012000Z][level:debug][msg:querying][request_id:abc][table:users]\n"+
+		"[ts:2019-04-08T20:21:33.520000Z][level:info][msg:request time][request_id:abc][elapsed:1.52]\n")
+
+	// The sources are as wide as the paths of the files.
+	pad := strings.Repeat(" ", len(a)-len("SOURCE"))
+
+	tt := []struct {
+		Name     string
+		Args     []string
+		Expected string
+	}{
+		{
+			Name: "Text",
+			Args: []string{"abc", a, b},
+			Expected: "request_id abc: 4 entries over 1.52s across 2 files, 1 error\n\n" +
+				"  OFFSET   DELTA  LEVEL  SOURCE" + pad + "  MESSAGE\n" +
+				"  +0s      +0s    DEBUG  " + a + "  handling request  url=/greet\n" +
+				"  +12ms    +12ms  DEBUG  " + b + "  querying  table=users\n" +
+				"          ~ 1.5s gap\n" +
+				"! +1.512s  +1.5s  ERROR  " + a + "  query failed  error=timeout\n" +
+				"  +1.52s   +8ms   INFO   " + b + "  request time  elapsed=1.52\n",
+		},
+		{
+			Name: "Colors",
+			Args: []string{"-color", "always", "-gap", "1.6s", "def", a},
+			Expected: "request_id def: 1 entry over 0s across 1 file, 0 errors\n\n" +
+				"\x1b[2m  OFFSET  DELTA  LEVEL  SOURCE" + pad + "  MESSAGE\x1b[0m\n" +
+				"  +0s     +0s    INFO   " + a + "  other request\n",
+		},
+		{
+			Name: "JSON",
+			Args: []string{"-output", "json", "-key", "table", "users", a, b},
+			Expected: "[\n" +
+				`  {"offset":0,"delta":0,"source":"` + b + `","gap":false,"error":false,"entry":{"ts":"2019-04-08T20:21:32.012000Z","level":"debug","msg":"querying","request_id":"abc","table":"users"}}` +
+				"\n]\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"trace"}, tc.Args...), nil, &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tc.Expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.Expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestTraceTruncated(t *testing.T) {
+	a := filepath.Join(t.TempDir(), "a.log")
+	writeFile(t, a, "[ts:2019-04-08T20:21:32.000000Z][msg:handling request][request_id:abc]\n"+
+		"[ts:2019-04-08T20:21:33.000000Z][msg:"+strings.Repeat("x", kvparse.DefaultMaxLineSize)+"][request_id:abc]\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"trace", "abc", a}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+	}
+	if expected := fmt.Sprintf("zapfmt trace: %s:2: line longer than %d bytes, truncated\n", a, kvparse.DefaultMaxLineSize); stderr.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, stderr.String())
+	}
+}
+
+func TestTraceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Args   []string
+		Code   int
+		Stderr string
+	}{
+		{[]string{"trace"}, 2, "usage: zapfmt trace"},
+		{[]string{"trace", "abc"}, 1, "no entries with request_id abc"},
+		{[]string{"trace", "-output", "xml", "abc"}, 1, "unknown output format"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(tc.Args, strings.NewReader(""), &stdout, &stderr); code != tc.Code || !strings.Contains(stderr.String(), tc.Stderr) {
+			t.Errorf("%v: expected exit code %d and %q, got %d and %q", tc.Args, tc.Code, tc.Stderr, code, stderr.String())
+		}
+	}
+}
@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 
+	"github.com/emiguens/zapfmt/internal/lazy"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -29,8 +30,19 @@ func Context(ctx context.Context, log Logger) context.Context {
 // API. Sugaring a logger is quite inexpensive, so it's reasonable for a
 // single application to use both Loggers and SugaredLoggers, converting
 // between them on the boundaries of performance-sensitive code.
+//
+// For one-off calls prefer the package functions such as Infof and Infow,
+// which avoid creating the SugaredLogger.
 func Sugar(ctx context.Context) *zap.SugaredLogger {
-	return getLogger(ctx).Sugar()
+	l := getLogger(ctx)
+
+	// The loggers of this package skip one frame for the package functions
+	// taking a context, which the SugaredLogger methods are not called
+	// through.
+	if pl, ok := l.(*logger); ok {
+		return pl.Logger.WithOptions(zap.AddCallerSkip(-1)).Sugar()
+	}
+	return l.Sugar()
 }
 
 // Named adds a new path segment to the logger's name. Segments are joined by
@@ -47,6 +59,28 @@ func With(ctx context.Context, fields ...zap.Field) context.Context {
 	return context.WithValue(ctx, contextKeyLogger, logger)
 }
 
+// WithLazy creates a child logger like With, but its fields are encoded,
+// and the values of lazy fields computed, only once the child writes an
+// entry. It's meant for fields added to every request, but expensive to
+// encode for requests that may log nothing.
+//
+// Loggers not implementing WithLazy themselves get the fields through With,
+// with the values of lazy fields computed right away.
+func WithLazy(ctx context.Context, fields ...zap.Field) context.Context {
+	var logger Logger
+	if l, ok := getLogger(ctx).(lazyLogger); ok {
+		logger = l.WithLazy(fields...)
+	} else {
+		logger = getLogger(ctx).With(lazy.Resolve(fields)...)
+	}
+	return context.WithValue(ctx, contextKeyLogger, logger)
+}
+
+// lazyLogger is implemented by the loggers able to defer encoding fields.
+type lazyLogger interface {
+	WithLazy(fields ...zap.Field) Logger
+}
+
 // WithLevel created a child logger that logs on the given level.
 // Child logger contains all fields from the parent.
 func WithLevel(ctx context.Context, lvl zapcore.Level) context.Context {
@@ -112,8 +146,16 @@ func Warn(ctx context.Context, msg string, fields ...zap.Field) {
 	getLogger(ctx).Warn(msg, fields...)
 }
 
-func getLogger(ctx context.Context) Logger {
+// FromContext returns the logger associated with the context by Context, and
+// whether there is one. Packages bridging other logging APIs use it to find
+// the logger of the context they're given.
+func FromContext(ctx context.Context) (Logger, bool) {
 	l, ok := ctx.Value(contextKeyLogger).(Logger)
+	return l, ok
+}
+
+func getLogger(ctx context.Context) Logger {
+	l, ok := FromContext(ctx)
 	if ok {
 		return l
 	}
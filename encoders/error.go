@@ -0,0 +1,288 @@
+package encoders
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorDetail is a set of details written along with the message of error
+// fields.
+type ErrorDetail uint8
+
+const (
+	// ErrorType writes the dynamic type of the error as <key>.type.
+	ErrorType ErrorDetail = 1 << iota
+	// ErrorChain writes the messages of the wrapped errors as <key>.chain.
+	// Errors wrapping several errors (such as the ones returned by
+	// errors.Join) are written as nested arrays, one per wrapped error.
+	ErrorChain
+	// ErrorStack writes the stack trace carried by the error as <key>.stack.
+	// Stack traces are taken from the deepest error in the chain providing
+	// one through StackTrace (github.com/pkg/errors), Callers or Stack methods.
+	ErrorStack
+
+	// AllErrorDetails writes all the available error details.
+	AllErrorDetails = ErrorType | ErrorChain | ErrorStack
+)
+
+// levelDetail holds the error details written from a level and above.
+type levelDetail struct {
+	lvl    zapcore.Level
+	detail ErrorDetail
+}
+
+// WithErrorDetail writes the given details for error fields (zap.Error and
+// zap.NamedError) logged in entries at lvl or above:
+//
+//	[error:read config: file not found][error.type:*fmt.wrapError][error.chain:[read config][file not found]]
+//
+// The option may be given several times to increase verbosity with the
+// level, the one with the highest level not above the entry level applies.
+// Only fields given at the log site are affected, as fields added through
+// With are encoded before the level is known.
+func WithErrorDetail(lvl zapcore.Level, detail ErrorDetail) Option {
+	return func(o *options) {
+		o.errorDetails = append(o.errorDetails, levelDetail{lvl: lvl, detail: detail})
+		sort.Slice(o.errorDetails, func(i, j int) bool {
+			return o.errorDetails[i].lvl < o.errorDetails[j].lvl
+		})
+	}
+}
+
+// errorDetail returns the error details to write at the given level.
+func (o *options) errorDetail(lvl zapcore.Level) ErrorDetail {
+	var detail ErrorDetail
+	for _, d := range o.errorDetails {
+		if d.lvl > lvl {
+			break
+		}
+		detail = d.detail
+	}
+	return detail
+}
+
+// RichError is shorthand for the common idiom NamedRichError("error", err).
+func RichError(err error) zapcore.Field {
+	return NamedRichError("error", err)
+}
+
+// NamedRichError constructs a field that lazily stores err, to be written
+// with its details by the key value encoder. Unlike zap.NamedError, details
+// are written even if WithErrorDetail was not given, in which case all of
+// them are written. Other encoders write it as an object.
+//
+// If err is nil the field is a no-op.
+func NamedRichError(key string, err error) zapcore.Field {
+	if err == nil {
+		return zapcore.Field{Type: zapcore.SkipType}
+	}
+	return zapcore.Field{Key: key, Type: zapcore.ObjectMarshalerType, Interface: richError{err}}
+}
+
+// richError allows encoders other than the key value one to write error
+// details as an object.
+type richError struct {
+	err error
+}
+
+func (e richError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.err.Error())
+	enc.AddString("type", fmt.Sprintf("%T", e.err))
+	if hasChain(e.err) {
+		if err := enc.AddArray("chain", errorChain{e.err}); err != nil {
+			return err
+		}
+	}
+	if stack := errorStack(e.err); stack != "" {
+		enc.AddString("stack", stack)
+	}
+	return nil
+}
+
+// richErrorDetail returns the details written for rich error fields.
+func (enc *kvEncoder) richErrorDetail() ErrorDetail {
+	if len(enc.opts.errorDetails) == 0 {
+		return AllErrorDetails
+	}
+	return enc.errDetail
+}
+
+// addFields adds the given fields, writing error fields with details.
+func (enc *kvEncoder) addFields(fields []zapcore.Field) {
+	for i := range fields {
+		f := &fields[i]
+		if f.Type == zapcore.ErrorType && enc.errDetail != 0 {
+			enc.addError(f.Key, f.Interface.(error), enc.errDetail)
+			continue
+		}
+		f.AddTo(enc)
+	}
+}
+
+// addError adds the error message and the requested details. A redaction
+// rule matching the key applies to the details too, as they disclose as much
+// of the error.
+func (enc *kvEncoder) addError(key string, err error, detail ErrorDetail) {
+	action, redacted := enc.keyRule(key)
+	add := func(k string, appendValue func(*kvEncoder)) {
+		a, ok := action, redacted
+		if !ok {
+			a, ok = enc.keyRule(k)
+		}
+		if ok {
+			enc.addRedacted(k, a, appendValue)
+			return
+		}
+		enc.addKey(k)
+		appendValue(enc)
+	}
+
+	add(key, func(e *kvEncoder) { e.AppendString(err.Error()) })
+	if detail&ErrorType != 0 {
+		add(key+".type", func(e *kvEncoder) { e.AppendString(fmt.Sprintf("%T", err)) })
+	}
+	if detail&ErrorChain != 0 && hasChain(err) {
+		add(key+".chain", func(e *kvEncoder) { e.AppendArray(errorChain{err}) })
+	}
+	if detail&ErrorStack != 0 {
+		if stack := errorStack(err); stack != "" {
+			add(key+".stack", func(e *kvEncoder) { e.AppendString(stack) })
+		}
+	}
+}
+
+type (
+	singleWrapper interface{ Unwrap() error }
+	multiWrapper  interface{ Unwrap() []error }
+	causer        interface{ Cause() error }
+	callersError  interface{ Callers() []uintptr }
+	stackError    interface{ Stack() []byte }
+)
+
+// unwrap returns the errors wrapped by err.
+func unwrap(err error) []error {
+	switch e := err.(type) {
+	case multiWrapper:
+		return e.Unwrap()
+	case singleWrapper:
+		if next := e.Unwrap(); next != nil {
+			return []error{next}
+		}
+	case causer:
+		if next := e.Cause(); next != nil {
+			return []error{next}
+		}
+	}
+	return nil
+}
+
+// hasChain reports whether err wraps other errors.
+func hasChain(err error) bool {
+	return len(unwrap(err)) > 0
+}
+
+// errorChain writes the messages of a chain of errors. Each error message is
+// stripped from the messages of the errors it wraps.
+type errorChain struct {
+	err error
+}
+
+func (c errorChain) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for err := c.err; err != nil; {
+		next := unwrap(err)
+		if msg := ownMessage(err, next); msg != "" {
+			enc.AppendString(msg)
+		}
+
+		switch len(next) {
+		case 0:
+			return nil
+		case 1:
+			err = next[0]
+		default:
+			for _, e := range next {
+				if err := enc.AppendArray(errorChain{e}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// ownMessage returns the part of the message of err not coming from the
+// errors it wraps, as in "<own message>: <wrapped message>".
+func ownMessage(err error, wrapped []error) string {
+	msg := err.Error()
+	if len(wrapped) == 0 {
+		return msg
+	}
+
+	msgs := make([]string, 0, len(wrapped))
+	for _, e := range wrapped {
+		msgs = append(msgs, e.Error())
+	}
+	inner := strings.Join(msgs, "\n")
+
+	if msg == inner {
+		return ""
+	}
+	return strings.TrimSuffix(msg, ": "+inner)
+}
+
+// errorStack returns the stack trace of the deepest error in the chain that
+// carries one.
+func errorStack(err error) string {
+	for _, e := range unwrap(err) {
+		if s := errorStack(e); s != "" {
+			return s
+		}
+	}
+	return ownStack(err)
+}
+
+// ownStack returns the stack trace carried by err itself.
+func ownStack(err error) string {
+	switch e := err.(type) {
+	case callersError:
+		return formatCallers(e.Callers())
+	case stackError:
+		return strings.TrimSpace(string(e.Stack()))
+	}
+
+	// Errors from github.com/pkg/errors return their own StackTrace type,
+	// which we don't want to depend on. It formats frames like zap does when
+	// given the %+v verb.
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return ""
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%+v", m.Call(nil)[0].Interface()), "\n")
+}
+
+// formatCallers formats program counters the way zap formats stack traces.
+func formatCallers(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+package encoders_test
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"go.uber.org/zap"
+)
+
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *wrapError) Unwrap() error { return e.err }
+
+type joinError struct {
+	errs []error
+}
+
+func (e *joinError) Error() string {
+	msgs := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *joinError) Unwrap() []error { return e.errs }
+
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 1)
+	runtime.Callers(1, pcs)
+	return &stackError{msg: msg, pcs: pcs}
+}
+
+func (e *stackError) Error() string      { return e.msg }
+func (e *stackError) Callers() []uintptr { return e.pcs }
+
+func TestErrorDetail(t *testing.T) {
+	chained := &wrapError{msg: "read config", err: &wrapError{msg: "open file", err: errors.New("not found")}}
+	joined := &wrapError{msg: "validate", err: &joinError{errs: []error{
+		&wrapError{msg: "name", err: errors.New("empty")},
+		errors.New("age is negative"),
+	}}}
+
+	tt := []struct {
+		Name     string
+		Options  []encoders.Option
+		Log      func(l *zap.Logger)
+		Expected string
+	}{
+		{
+			Name: "Plain Errors Without Option",
+			Log: func(l *zap.Logger) {
+				l.Error("my error message", zap.Error(chained))
+			},
+			Expected: "[msg:my error message][error:read config: open file: not found]",
+		},
+		{
+			Name:    "Unwrap Chain",
+			Options: []encoders.Option{encoders.WithErrorDetail(zap.DebugLevel, encoders.ErrorType|encoders.ErrorChain)},
+			Log: func(l *zap.Logger) {
+				l.Error("my error message", zap.Error(chained))
+			},
+			Expected: "[msg:my error message][error:read config: open file: not found][error.type:*encoders_test.wrapError][error.chain:[read config][open file][not found]]",
+		},
+		{
+			Name:    "Join Tree",
+			Options: []encoders.Option{encoders.WithErrorDetail(zap.DebugLevel, encoders.ErrorChain)},
+			Log: func(l *zap.Logger) {
+				l.Error("my error message", zap.NamedError("cause", joined))
+			},
+			Expected: "[msg:my error message][cause:validate: name: empty\\nage is negative][cause.chain:[validate][[name][empty]][[age is negative]]]",
+		},
+		{
+			Name: "Verbosity Per Level",
+			Options: []encoders.Option{
+				encoders.WithErrorDetail(zap.WarnLevel, encoders.ErrorType),
+				encoders.WithErrorDetail(zap.ErrorLevel, encoders.ErrorChain),
+			},
+			Log: func(l *zap.Logger) {
+				l.Info("my info message", zap.Error(chained))
+				l.Warn("my warn message", zap.Error(chained))
+				l.Error("my error message", zap.Error(chained))
+			},
+			Expected: "[msg:my info message][error:read config: open file: not found]" +
+				"[msg:my warn message][error:read config: open file: not found][error.type:*encoders_test.wrapError]" +
+				"[msg:my error message][error:read config: open file: not found][error.chain:[read config][open file][not found]]",
+		},
+		{
+			Name: "Rich Error Field",
+			Log: func(l *zap.Logger) {
+				l.Error("my error message", encoders.RichError(&wrapError{msg: "wrapped", err: errors.New("cause")}))
+			},
+			Expected: "[msg:my error message][error:wrapped: cause][error.type:*encoders_test.wrapError][error.chain:[wrapped][cause]]",
+		},
+		{
+			Name: "Redacted Rich Error Field",
+			Options: []encoders.Option{encoders.WithRedaction(encoders.Redaction{
+				Keys: []encoders.KeyRule{{Pattern: "cause", Action: encoders.Mask}},
+			})},
+			Log: func(l *zap.Logger) {
+				l.Error("my error message", encoders.NamedRichError("cause", &wrapError{msg: "wrapped", err: errors.New("cause")}))
+			},
+			Expected: "[msg:my error message][cause:***][cause.type:***][cause.chain:***]",
+		},
+		{
+			Name: "Redacted Error Details",
+			Options: []encoders.Option{
+				encoders.WithErrorDetail(zap.DebugLevel, encoders.ErrorType|encoders.ErrorChain),
+				encoders.WithRedaction(encoders.Redaction{
+					Keys: []encoders.KeyRule{{Pattern: "error", Action: encoders.Drop}},
+				}),
+			},
+			Log: func(l *zap.Logger) {
+				l.Error("my error message", zap.Error(chained))
+			},
+			Expected: "[msg:my error message]",
+		},
+		{
+			Name: "Nil Rich Error Field",
+			Log: func(l *zap.Logger) {
+				l.Error("my error message", encoders.RichError(nil))
+			},
+			Expected: "[msg:my error message]",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			logger, buffer := testLogger(tc.Options...)
+			tc.Log(logger)
+
+			var msgs []string
+			for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+				_, msg := deconstructLogLine(line)
+				msgs = append(msgs, msg)
+			}
+			requireEqual(t, tc.Expected, strings.Join(msgs, ""))
+		})
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	logger, buffer := testLogger(encoders.WithErrorDetail(zap.DebugLevel, encoders.ErrorStack))
+	logger.Error("my error message", zap.Error(&wrapError{msg: "wrapped", err: newStackError("cause")}))
+
+	_, msg := deconstructLogLine(buffer.String())
+	const prefix = "[msg:my error message][error:wrapped: cause][error.stack:github.com/emiguens/zapfmt/encoders_test.newStackError\\n\\t"
+	if !strings.HasPrefix(msg, prefix) {
+		t.Fatalf("expected message to start with %s, got: %s", prefix, msg)
+	}
+}
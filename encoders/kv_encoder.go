@@ -41,20 +41,63 @@ func putKeyValueEncoder(enc *kvEncoder) {
 		enc.reflectBuf.Free()
 	}
 	enc.EncoderConfig = nil
+	enc.opts = nil
 	enc.buf = nil
 	enc.spaced = false
 	enc.openNamespaces = 0
+	enc.errDetail = 0
+	enc.depth = 0
+	enc.truncated = false
+	enc.header = false
+	enc.spans = enc.spans[:0]
+	enc.lineFields = enc.lineFields[:0]
 	enc.reflectBuf = nil
 	enc.reflectEnc = nil
 	_encoderPool.Put(enc)
 }
 
+// Option configures optional behaviour of the key value encoder.
+type Option func(*options)
+
+// options holds the optional behaviour of a key value encoder. It's shared
+// between an encoder and all of its clones.
+type options struct {
+	redactor     *redactor
+	reflection   *Reflection
+	errorDetails []levelDetail
+	limits       *Limits
+	truncated    *Counter
+
+	dedupe         Dedupe
+	sortFields     bool
+	reservedPrefix string
+}
+
 type kvEncoder struct {
 	*zapcore.EncoderConfig
+	opts           *options
 	buf            *buffer.Buffer
 	spaced         bool // include spaces after colons and commas
 	openNamespaces int
 
+	// error details written for error fields of the entry being encoded
+	errDetail ErrorDetail
+
+	// nesting of objects and arrays being encoded, and whether anything was
+	// truncated because of the limits
+	depth     int
+	truncated bool
+
+	// whether the entry metadata (time, level, name, caller, message and
+	// stacktrace) is being encoded, which is never truncated nor reordered
+	header bool
+
+	// top-level fields added so far, when they must be reordered
+	spans []fieldSpan
+
+	// top-level fields of the entry, when it may be cut to the line limit
+	lineFields []lineField
+
 	// for encoding generic values by reflection
 	reflectBuf *buffer.Buffer
 	reflectEnc *json.Encoder
@@ -63,30 +106,55 @@ type kvEncoder struct {
 // NewKeyValueEncoder creates a fast, low-allocation logging encoder. The encoder
 // appropriately escapes all field keys and values.
 //
-// Note that the encoder doesn't deduplicate keys, so it's possible to produce
-// a message like
-//   [foo:bar][foo:baz]
+// Note that by default the encoder doesn't deduplicate keys, so it's possible
+// to produce a message like
+//
+//	[foo:bar][foo:baz]
+//
 // Although this is permitted, it's not encouraged. Many libraries will ignore
 // duplicate key-value pairs (typically keeping the last pair) when
-// unmarshalling, so you should try to avoid adding duplicate keys.
-func NewKeyValueEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
-	return newKeyValueEncoder(cfg, false)
+// unmarshalling, so you should try to avoid adding duplicate keys, or use the
+// WithDedupe option.
+//
+// Optional behaviour, such as redaction, is enabled through the given options.
+func NewKeyValueEncoder(cfg zapcore.EncoderConfig, opts ...Option) zapcore.Encoder {
+	return newKeyValueEncoder(cfg, false, opts...)
 }
 
-func newKeyValueEncoder(cfg zapcore.EncoderConfig, spaced bool) *kvEncoder {
+func newKeyValueEncoder(cfg zapcore.EncoderConfig, spaced bool, opts ...Option) *kvEncoder {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return &kvEncoder{
 		EncoderConfig: &cfg,
+		opts:          o,
 		buf:           getBufferPool(),
 		spaced:        spaced,
 	}
 }
 
 func (enc *kvEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	if action, ok := enc.keyRule(key); ok {
+		var err error
+		enc.addRedacted(key, action, func(e *kvEncoder) { err = e.AppendArray(arr) })
+		return err
+	}
 	enc.addKey(key)
 	return enc.AppendArray(arr)
 }
 
 func (enc *kvEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if e, ok := obj.(richError); ok {
+		enc.addError(key, e.err, enc.richErrorDetail())
+		return nil
+	}
+	if action, ok := enc.keyRule(key); ok {
+		var err error
+		enc.addRedacted(key, action, func(e *kvEncoder) { err = e.AppendObject(obj) })
+		return err
+	}
 	enc.addKey(key)
 	return enc.AppendObject(obj)
 }
@@ -96,31 +164,55 @@ func (enc *kvEncoder) AddBinary(key string, val []byte) {
 }
 
 func (enc *kvEncoder) AddByteString(key string, val []byte) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendByteString(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendByteString(val)
 }
 
 func (enc *kvEncoder) AddBool(key string, val bool) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendBool(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendBool(val)
 }
 
 func (enc *kvEncoder) AddComplex128(key string, val complex128) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendComplex128(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendComplex128(val)
 }
 
 func (enc *kvEncoder) AddDuration(key string, val time.Duration) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendDuration(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendDuration(val)
 }
 
 func (enc *kvEncoder) AddFloat64(key string, val float64) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendFloat64(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendFloat64(val)
 }
 
 func (enc *kvEncoder) AddInt64(key string, val int64) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendInt64(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendInt64(val)
 }
@@ -135,50 +227,107 @@ func (enc *kvEncoder) resetReflectBuf() {
 }
 
 func (enc *kvEncoder) AddReflected(key string, obj interface{}) error {
+	if action, ok := enc.keyRule(key); ok {
+		var err error
+		enc.addRedacted(key, action, func(e *kvEncoder) { err = e.AppendReflected(obj) })
+		return err
+	}
+	if enc.opts.reflection != nil {
+		enc.addKey(key)
+		return enc.appendReflectedValue(obj)
+	}
+	err := enc.encodeReflected(obj)
+	if err != nil {
+		return err
+	}
+	enc.addKey(key)
+	return enc.appendRaw(enc.reflectBuf.Bytes(), enc.valueLimit())
+}
+
+// encodeReflected leaves the JSON encoding of obj in the reflection buffer,
+// redacting it if needed.
+func (enc *kvEncoder) encodeReflected(obj interface{}) error {
 	enc.resetReflectBuf()
 	err := enc.reflectEnc.Encode(obj)
 	if err != nil {
 		return err
 	}
+	if r := enc.opts.redactor; r != nil {
+		if err := r.redactJSON(enc.reflectBuf); err != nil {
+			return err
+		}
+	}
 	enc.reflectBuf.TrimNewline()
-	enc.addKey(key)
-	_, err = enc.buf.Write(enc.reflectBuf.Bytes())
-	return err
+	return nil
 }
 
 func (enc *kvEncoder) OpenNamespace(key string) {
 	enc.addKey(key)
+	enc.nestLineField()
 	enc.buf.AppendByte('{')
 	enc.openNamespaces++
 }
 
 func (enc *kvEncoder) AddString(key, val string) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendString(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendString(val)
 }
 
 func (enc *kvEncoder) AddTime(key string, val time.Time) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendTime(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendTime(val)
 }
 
 func (enc *kvEncoder) AddUint64(key string, val uint64) {
+	if action, ok := enc.keyRule(key); ok {
+		enc.addRedacted(key, action, func(e *kvEncoder) { e.AppendUint64(val) })
+		return
+	}
 	enc.addKey(key)
 	enc.AppendUint64(val)
 }
 
 func (enc *kvEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	if enc.nestTooDeep() {
+		return nil
+	}
+	enc.nestLineField()
 	enc.addElementSeparator()
 	enc.buf.AppendByte('[')
-	err := arr.MarshalLogArray(enc)
+	enc.depth++
+
+	var err error
+	if max := enc.opts.limit().MaxElements; max > 0 {
+		limited := &limitedArrayEncoder{enc: enc, max: max}
+		err = arr.MarshalLogArray(limited)
+		limited.close()
+	} else {
+		err = arr.MarshalLogArray(enc)
+	}
+
+	enc.depth--
 	enc.buf.AppendByte(']')
 	return err
 }
 
 func (enc *kvEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	if enc.nestTooDeep() {
+		return nil
+	}
+	enc.nestLineField()
 	enc.addElementSeparator()
 	enc.buf.AppendByte('{')
+	enc.depth++
 	err := obj.MarshalLogObject(enc)
+	enc.depth--
 	enc.buf.AppendByte('}')
 	return err
 }
@@ -190,7 +339,11 @@ func (enc *kvEncoder) AppendBool(val bool) {
 
 func (enc *kvEncoder) AppendByteString(val []byte) {
 	enc.addElementSeparator()
-	enc.safeAddByteString(val)
+	if r := enc.opts.redactor; r != nil && r.redactsValues() {
+		enc.appendString(string(val), enc.valueLimit())
+		return
+	}
+	enc.appendByteString(val, enc.valueLimit())
 }
 
 func (enc *kvEncoder) AppendComplex128(val complex128) {
@@ -221,20 +374,20 @@ func (enc *kvEncoder) AppendInt64(val int64) {
 }
 
 func (enc *kvEncoder) AppendReflected(val interface{}) error {
-	enc.resetReflectBuf()
-	err := enc.reflectEnc.Encode(val)
+	if enc.opts.reflection != nil {
+		return enc.appendReflectedValue(val)
+	}
+	err := enc.encodeReflected(val)
 	if err != nil {
 		return err
 	}
-	enc.reflectBuf.TrimNewline()
 	enc.addElementSeparator()
-	_, err = enc.buf.Write(enc.reflectBuf.Bytes())
-	return err
+	return enc.appendRaw(enc.reflectBuf.Bytes(), enc.valueLimit())
 }
 
 func (enc *kvEncoder) AppendString(val string) {
 	enc.addElementSeparator()
-	enc.safeAddString(val)
+	enc.appendString(val, enc.valueLimit())
 }
 
 func (enc *kvEncoder) AppendTime(val time.Time) {
@@ -279,20 +432,25 @@ func (enc *kvEncoder) AppendUintptr(v uintptr)            { enc.AppendUint64(uin
 func (enc *kvEncoder) Clone() zapcore.Encoder {
 	clone := enc.clone()
 	clone.buf.Write(enc.buf.Bytes())
+	clone.spans = append(clone.spans, enc.spans...)
 	return clone
 }
 
 func (enc *kvEncoder) clone() *kvEncoder {
 	clone := getKeyValueEncoder()
 	clone.EncoderConfig = enc.EncoderConfig
+	clone.opts = enc.opts
 	clone.spaced = enc.spaced
 	clone.openNamespaces = enc.openNamespaces
+	clone.truncated = enc.truncated
 	clone.buf = getBufferPool()
 	return clone
 }
 
 func (enc *kvEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
 	final := enc.clone()
+	final.errDetail = enc.opts.errorDetail(ent.Level)
+	final.header = true
 	final.buf.AppendByte('[')
 
 	if final.TimeKey != "" {
@@ -338,17 +496,28 @@ func (enc *kvEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*b
 	}
 	if final.MessageKey != "" {
 		final.addKey(enc.MessageKey)
-		final.AppendString(ent.Message)
-	}
-	if enc.buf.Len() > 0 {
 		final.addElementSeparator()
-		final.buf.Write(enc.buf.Bytes())
+		final.appendString(ent.Message, final.opts.limit().MaxMessage)
 	}
-	addFields(final, fields)
-	final.closeOpenNamespaces()
+	final.header = false
+	if enc.opts.reorders() {
+		enc.addOrderedFields(final, fields)
+	} else {
+		if enc.buf.Len() > 0 {
+			if final.tracksLine() {
+				final.addLineField(final.buf.Len(), -1, false)
+			}
+			final.addElementSeparator()
+			final.buf.Write(enc.buf.Bytes())
+		}
+		final.addFields(fields)
+		final.closeOpenNamespaces()
+	}
+	final.header = true
 	if ent.Stack != "" && final.StacktraceKey != "" {
 		final.AddString(final.StacktraceKey, ent.Stack)
 	}
+	final.limitLine()
 	final.buf.AppendByte(']')
 	if final.LineEnding != "" {
 		final.buf.AppendString(final.LineEnding)
@@ -356,6 +525,10 @@ func (enc *kvEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*b
 		final.buf.AppendString(zapcore.DefaultLineEnding)
 	}
 
+	if final.truncated {
+		final.opts.countTruncated()
+	}
+
 	ret := final.buf
 	putKeyValueEncoder(final)
 	return ret, nil
@@ -372,12 +545,24 @@ func (enc *kvEncoder) closeOpenNamespaces() {
 }
 
 func (enc *kvEncoder) addKey(key string) {
-	enc.addElementSeparator()
+	sep := enc.buf.Len()
+	topLevel := enc.atTopLevel()
+	if topLevel && enc.opts.reorders() {
+		enc.trackKey(key)
+	} else {
+		enc.addElementSeparator()
+	}
+	if topLevel && enc.opts.reservedPrefix != "" && enc.isReserved(key) {
+		enc.safeAddString(enc.opts.reservedPrefix)
+	}
 	enc.safeAddString(key)
 	enc.buf.AppendByte(':')
 	if enc.spaced {
 		enc.buf.AppendByte(' ')
 	}
+	if enc.tracksLine() {
+		enc.addLineField(sep, enc.buf.Len(), true)
+	}
 }
 
 func (enc *kvEncoder) addElementSeparator() {
@@ -485,9 +670,3 @@ func (enc *kvEncoder) tryAddRuneError(r rune, size int) bool {
 	}
 	return false
 }
-
-func addFields(enc zapcore.ObjectEncoder, fields []zapcore.Field) {
-	for i := range fields {
-		fields[i].AddTo(enc)
-	}
-}
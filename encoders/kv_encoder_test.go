@@ -108,7 +108,7 @@ func deconstructLogLine(line string) (level, content string) {
 	return matches[0][1], matches[0][2]
 }
 
-func testLogger() (*zap.Logger, *bytes.Buffer) {
+func testLogger(opts ...encoders.Option) (*zap.Logger, *bytes.Buffer) {
 	buf := new(bytes.Buffer)
 	writer := zapcore.Lock(zapcore.AddSync(buf))
 
@@ -117,7 +117,7 @@ func testLogger() (*zap.Logger, *bytes.Buffer) {
 		enc.AppendString(time.Unix(0, 0).UTC().Format(time.RFC3339))
 	}
 
-	encoder := encoders.NewKeyValueEncoder(encoderConfig)
+	encoder := encoders.NewKeyValueEncoder(encoderConfig, opts...)
 
 	core := zapcore.NewCore(encoder, writer, zap.NewAtomicLevelAt(zap.DebugLevel))
 
@@ -0,0 +1,418 @@
+package encoders
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// lineLimitReserve is the room kept at the end of a line truncated because of
+// Limits.MaxLine, so that the truncation marker fits within the limit.
+const lineLimitReserve = 32
+
+// Limits bounds the size of encoded entries. Zero values mean no limit.
+//
+// Truncated strings end with a marker telling how much was removed, like
+// "…(truncated 4.2MB)". Arrays keep their first elements followed by a
+// "…(N more)" element, and objects or arrays nested too deep are replaced
+// by "…". Reflected values embedded as JSON, and objects or arrays cut by
+// the line limit, are replaced by the marker rather than left unclosed.
+type Limits struct {
+	// MaxMessage limits the bytes of the entry message.
+	MaxMessage int
+
+	// MaxValue limits the bytes of each string, byte string and reflected
+	// value.
+	MaxValue int
+
+	// MaxElements limits the number of elements of each array.
+	MaxElements int
+
+	// MaxDepth limits the nesting of objects and arrays.
+	MaxDepth int
+
+	// MaxLine limits the bytes of a whole encoded entry, not counting the
+	// line ending. It shouldn't be lower than a few dozen bytes.
+	MaxLine int
+
+	// Truncated, if not nil, counts the entries that had anything truncated.
+	Truncated *Counter
+}
+
+// Counter is a monotonic counter, safe for concurrent use.
+type Counter struct {
+	n uint64
+}
+
+// Load returns the current count.
+func (c *Counter) Load() uint64 {
+	return atomic.LoadUint64(&c.n)
+}
+
+func (c *Counter) inc() {
+	atomic.AddUint64(&c.n, 1)
+}
+
+// WithLimits bounds the size of encoded entries.
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = &l
+	}
+}
+
+// WithTruncatedCounter counts into c the entries that had anything
+// truncated, along with Limits.Truncated, so that a counter can be given
+// apart from the limits. Nothing is truncated unless limits are given through
+// WithLimits.
+func WithTruncatedCounter(c *Counter) Option {
+	return func(o *options) {
+		o.truncated = c
+	}
+}
+
+// countTruncated counts an entry that had anything truncated, once in each
+// configured counter.
+func (o *options) countTruncated() {
+	c := o.limit().Truncated
+	if c != nil {
+		c.inc()
+	}
+	if o.truncated != nil && o.truncated != c {
+		o.truncated.inc()
+	}
+}
+
+// limit returns the configured limits, which are all zero if none.
+func (o *options) limit() *Limits {
+	if o.limits == nil {
+		return &_noLimits
+	}
+	return o.limits
+}
+
+var _noLimits Limits
+
+// valueLimit returns the maximum bytes of the value being encoded.
+func (enc *kvEncoder) valueLimit() int {
+	if enc.header {
+		return 0
+	}
+	return enc.opts.limit().MaxValue
+}
+
+// appendString appends the redacted value truncated to limit bytes.
+func (enc *kvEncoder) appendString(val string, limit int) {
+	if r := enc.opts.redactor; r != nil {
+		val = r.redactString(val)
+	}
+	if limit <= 0 || len(val) <= limit {
+		enc.safeAddString(val)
+		return
+	}
+
+	n := limit
+	for n > 0 && !utf8.RuneStart(val[n]) {
+		n--
+	}
+	enc.safeAddString(val[:n])
+	enc.appendTruncation(len(val) - n)
+}
+
+// appendByteString appends the value truncated to limit bytes.
+func (enc *kvEncoder) appendByteString(val []byte, limit int) {
+	if limit <= 0 || len(val) <= limit {
+		enc.safeAddByteString(val)
+		return
+	}
+
+	n := limit
+	for n > 0 && !utf8.RuneStart(val[n]) {
+		n--
+	}
+	enc.safeAddByteString(val[:n])
+	enc.appendTruncation(len(val) - n)
+}
+
+// appendRaw writes already encoded bytes, or only the truncation marker if
+// they exceed limit bytes, as cutting them would leave their brackets open.
+func (enc *kvEncoder) appendRaw(val []byte, limit int) error {
+	enc.nestLineField()
+	if limit <= 0 || len(val) <= limit {
+		_, err := enc.buf.Write(val)
+		return err
+	}
+	enc.appendTruncation(len(val))
+	return nil
+}
+
+// appendTruncation writes the marker for the given number of removed bytes.
+func (enc *kvEncoder) appendTruncation(removed int) {
+	enc.truncated = true
+	enc.buf.AppendString("…(truncated ")
+	appendSize(enc, removed)
+	enc.buf.AppendByte(')')
+}
+
+// appendSize writes a human readable amount of bytes, like 4.2MB.
+func appendSize(enc *kvEncoder, n int) {
+	const units = "KMGTPE"
+	if n < 1024 {
+		enc.buf.AppendInt(int64(n))
+		enc.buf.AppendByte('B')
+		return
+	}
+
+	size, unit := float64(n)/1024, 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	var scratch [24]byte
+	enc.buf.Write(strconv.AppendFloat(scratch[:0], size, 'f', 1, 64))
+	enc.buf.AppendByte(units[unit])
+	enc.buf.AppendByte('B')
+}
+
+// nestTooDeep writes a marker in place of an object or array exceeding the
+// depth limit, returning true if so.
+func (enc *kvEncoder) nestTooDeep() bool {
+	max := enc.opts.limit().MaxDepth
+	if max <= 0 || enc.depth < max {
+		return false
+	}
+	enc.truncated = true
+	enc.addElementSeparator()
+	enc.buf.AppendString("…")
+	return true
+}
+
+// lineField locates a top-level field of the entry being encoded, so that a
+// line exceeding Limits.MaxLine is cut where it stays well formed.
+type lineField struct {
+	// sep is where the separator preceding the field starts, and value where
+	// its value starts, or -1 if unknown.
+	sep, value int
+	// flat is whether the value holds no objects, arrays nor raw JSON, so it
+	// can be cut anywhere but within an escape sequence.
+	flat bool
+}
+
+// tracksLine reports whether top-level fields are tracked for the line limit.
+func (enc *kvEncoder) tracksLine() bool {
+	return enc.opts.limit().MaxLine > 0 && enc.depth == 0 && enc.openNamespaces == 0
+}
+
+// addLineField records a top-level field whose separator starts at sep.
+func (enc *kvEncoder) addLineField(sep, value int, flat bool) {
+	enc.lineFields = append(enc.lineFields, lineField{sep: sep, value: value, flat: flat})
+}
+
+// nestLineField records that the top-level field being written holds nested
+// values.
+func (enc *kvEncoder) nestLineField() {
+	if n := len(enc.lineFields); n > 0 && enc.tracksLine() {
+		enc.lineFields[n-1].flat = false
+	}
+}
+
+// limitLine truncates the entry being encoded to the line limit. A plain
+// value is cut at the limit, while a nested value, or a field whose value
+// isn't located, is replaced by the truncation marker altogether.
+func (enc *kvEncoder) limitLine() {
+	max := enc.opts.limit().MaxLine
+	if max <= 0 || enc.buf.Len() <= max {
+		return
+	}
+
+	b := enc.buf.Bytes()
+	n := max - lineLimitReserve
+	if n < 0 {
+		n = 0
+	}
+
+	cut, whole := cutPoint(b, 0, n), false
+	for i := len(enc.lineFields) - 1; i >= 0; i-- {
+		f := enc.lineFields[i]
+		if f.sep >= n {
+			continue
+		}
+		switch {
+		case f.value >= 0 && f.value <= n && f.flat:
+			cut = cutPoint(b, f.value, n)
+		case f.value >= 0 && f.value <= n:
+			cut = f.value
+		default:
+			cut, whole = f.sep, true
+		}
+		break
+	}
+	removed := len(b) - cut
+
+	// Writing a prefix of the buffer onto itself doesn't allocate.
+	enc.buf.Reset()
+	enc.buf.Write(b[:cut])
+	if whole {
+		enc.addElementSeparator()
+	}
+	enc.appendTruncation(removed)
+}
+
+// cutPoint returns where to cut the encoded value starting at from so that
+// it's at most n bytes, without splitting a rune nor an escape sequence.
+func cutPoint(b []byte, from, n int) int {
+	for n > from && !utf8.RuneStart(b[n]) {
+		n--
+	}
+
+	// The backslash closest to the cut, if any, either starts an escape or
+	// ends an escaped backslash. Escapes are at most 6 bytes long (\uXXXX).
+	for i := n - 1; i >= from && i > n-6; i-- {
+		if b[i] != '\\' {
+			continue
+		}
+		j := i
+		for j > from && b[j-1] == '\\' {
+			j--
+		}
+		if (i-j)%2 == 1 {
+			// An escaped backslash, which ends before the cut.
+			break
+		}
+		size := 2
+		if i+1 < len(b) && b[i+1] == 'u' {
+			size = 6
+		}
+		if i+size > n {
+			n = i
+		}
+		break
+	}
+	return n
+}
+
+// limitedArrayEncoder drops the elements of an array beyond a limit.
+type limitedArrayEncoder struct {
+	enc     *kvEncoder
+	max     int
+	n       int
+	skipped int
+}
+
+// skip reports whether the next element must be dropped.
+func (a *limitedArrayEncoder) skip() bool {
+	if a.n < a.max {
+		a.n++
+		return false
+	}
+	a.skipped++
+	return true
+}
+
+// close writes the marker for the dropped elements, if any.
+func (a *limitedArrayEncoder) close() {
+	if a.skipped == 0 {
+		return
+	}
+	enc := a.enc
+	enc.truncated = true
+	enc.addElementSeparator()
+	enc.buf.AppendString("…(")
+	enc.buf.AppendInt(int64(a.skipped))
+	enc.buf.AppendString(" more)")
+}
+
+func (a *limitedArrayEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	if a.skip() {
+		return nil
+	}
+	return a.enc.AppendArray(v)
+}
+
+func (a *limitedArrayEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	if a.skip() {
+		return nil
+	}
+	return a.enc.AppendObject(v)
+}
+
+func (a *limitedArrayEncoder) AppendReflected(v interface{}) error {
+	if a.skip() {
+		return nil
+	}
+	return a.enc.AppendReflected(v)
+}
+
+func (a *limitedArrayEncoder) AppendBool(v bool) {
+	if !a.skip() {
+		a.enc.AppendBool(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendByteString(v []byte) {
+	if !a.skip() {
+		a.enc.AppendByteString(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendComplex128(v complex128) {
+	if !a.skip() {
+		a.enc.AppendComplex128(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendDuration(v time.Duration) {
+	if !a.skip() {
+		a.enc.AppendDuration(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendFloat64(v float64) {
+	if !a.skip() {
+		a.enc.AppendFloat64(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendFloat32(v float32) {
+	if !a.skip() {
+		a.enc.AppendFloat32(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendInt64(v int64) {
+	if !a.skip() {
+		a.enc.AppendInt64(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendString(v string) {
+	if !a.skip() {
+		a.enc.AppendString(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendTime(v time.Time) {
+	if !a.skip() {
+		a.enc.AppendTime(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendUint64(v uint64) {
+	if !a.skip() {
+		a.enc.AppendUint64(v)
+	}
+}
+
+func (a *limitedArrayEncoder) AppendComplex64(v complex64) { a.AppendComplex128(complex128(v)) }
+func (a *limitedArrayEncoder) AppendInt(v int)             { a.AppendInt64(int64(v)) }
+func (a *limitedArrayEncoder) AppendInt32(v int32)         { a.AppendInt64(int64(v)) }
+func (a *limitedArrayEncoder) AppendInt16(v int16)         { a.AppendInt64(int64(v)) }
+func (a *limitedArrayEncoder) AppendInt8(v int8)           { a.AppendInt64(int64(v)) }
+func (a *limitedArrayEncoder) AppendUint(v uint)           { a.AppendUint64(uint64(v)) }
+func (a *limitedArrayEncoder) AppendUint32(v uint32)       { a.AppendUint64(uint64(v)) }
+func (a *limitedArrayEncoder) AppendUint16(v uint16)       { a.AppendUint64(uint64(v)) }
+func (a *limitedArrayEncoder) AppendUint8(v uint8)         { a.AppendUint64(uint64(v)) }
+func (a *limitedArrayEncoder) AppendUintptr(v uintptr)     { a.AppendUint64(uint64(v)) }
@@ -0,0 +1,152 @@
+package encoders_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type nested struct {
+	depth int
+}
+
+func (n nested) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("depth", n.depth)
+	return enc.AddObject("child", nested{n.depth + 1})
+}
+
+func TestLimits(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Limits   encoders.Limits
+		Message  string
+		Fields   []zap.Field
+		Expected string
+	}{
+		{
+			Name:     "Message",
+			Limits:   encoders.Limits{MaxMessage: 5},
+			Message:  "my debug message",
+			Fields:   []zap.Field{zap.String("key", "my value")},
+			Expected: "[msg:my de…(truncated 11B)][key:my value]",
+		},
+		{
+			Name:     "Values",
+			Limits:   encoders.Limits{MaxValue: 4},
+			Message:  "my debug message",
+			Fields:   []zap.Field{zap.String("key", "my value"), zap.ByteString("bytes", []byte(strings.Repeat("a", 5*1024*1024)))},
+			Expected: "[msg:my debug message][key:my v…(truncated 4B)][bytes:aaaa…(truncated 5.0MB)]",
+		},
+		{
+			Name:     "Values Keep Runes Whole",
+			Limits:   encoders.Limits{MaxMessage: 4},
+			Message:  "ñañ",
+			Expected: "[msg:ña…(truncated 2B)]",
+		},
+		{
+			Name:     "Reflected Values",
+			Limits:   encoders.Limits{MaxValue: 8},
+			Message:  "msg",
+			Fields:   []zap.Field{zap.Reflect("map", map[string]string{"key": "value"})},
+			Expected: `[msg:msg][map:…(truncated 15B)]`,
+		},
+		{
+			Name:     "Array Elements",
+			Limits:   encoders.Limits{MaxElements: 2},
+			Message:  "msg",
+			Fields:   []zap.Field{zap.Ints("numbers", []int{1, 2, 3, 4, 5}), zap.Strings("strings", []string{"a"})},
+			Expected: "[msg:msg][numbers:[1][2][…(3 more)]][strings:[a]]",
+		},
+		{
+			Name:     "Depth",
+			Limits:   encoders.Limits{MaxDepth: 2},
+			Message:  "msg",
+			Fields:   []zap.Field{zap.Object("obj", nested{})},
+			Expected: "[msg:msg][obj:{depth:0][child:{depth:1][child:…}}]",
+		},
+		{
+			Name:     "Line",
+			Limits:   encoders.Limits{MaxLine: 100},
+			Message:  "msg",
+			Fields:   []zap.Field{zap.String("key", strings.Repeat("a", 100))},
+			Expected: "[msg:msg][key:aaaaaaaaaaaaaaaa…(truncated 84B)]",
+		},
+		{
+			Name:     "Line Keeps Escapes Whole",
+			Limits:   encoders.Limits{MaxLine: 100},
+			Message:  "msg",
+			Fields:   []zap.Field{zap.String("key", "a"+strings.Repeat(`"`, 100))},
+			Expected: `[msg:msg][key:a\"\"\"\"\"\"\"…(truncated 186B)]`,
+		},
+		{
+			Name:     "Line Replaces Nested Values",
+			Limits:   encoders.Limits{MaxLine: 100},
+			Message:  "msg",
+			Fields:   []zap.Field{zap.Int("count", 1), zap.Strings("key", []string{strings.Repeat("a", 100)})},
+			Expected: "[msg:msg][count:1][key:…(truncated 102B)]",
+		},
+		{
+			Name:     "Line Replaces Reflected Values",
+			Limits:   encoders.Limits{MaxLine: 100},
+			Message:  "msg",
+			Fields:   []zap.Field{zap.Reflect("key", map[string]string{"key": strings.Repeat("a", 100)})},
+			Expected: "[msg:msg][key:…(truncated 110B)]",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			counter := &encoders.Counter{}
+			tc.Limits.Truncated = counter
+
+			logger, buffer := testLogger(encoders.WithLimits(tc.Limits))
+			logger.Debug(tc.Message, tc.Fields...)
+
+			_, msg := deconstructLogLine(buffer.String())
+			requireEqual(t, tc.Expected, msg)
+			requireEqual(t, uint64(1), counter.Load())
+		})
+	}
+}
+
+func TestLimitsCountTruncatedEntries(t *testing.T) {
+	counter := &encoders.Counter{}
+	logger, _ := testLogger(encoders.WithLimits(encoders.Limits{MaxValue: 4, Truncated: counter}))
+
+	logger.Debug("msg")
+	logger.Debug("msg", zap.String("key", "short"))
+	logger.Debug("msg", zap.String("key", "long value"))
+
+	requireEqual(t, uint64(2), counter.Load())
+}
+
+func TestLimitsTruncatedCounter(t *testing.T) {
+	limitsCounter, counter := &encoders.Counter{}, &encoders.Counter{}
+	logger, _ := testLogger(
+		encoders.WithTruncatedCounter(counter),
+		encoders.WithLimits(encoders.Limits{MaxValue: 4, Truncated: limitsCounter}),
+	)
+	logger.Debug("msg", zap.String("key", "long value"))
+
+	shared := &encoders.Counter{}
+	logger, _ = testLogger(
+		encoders.WithTruncatedCounter(shared),
+		encoders.WithLimits(encoders.Limits{MaxValue: 4, Truncated: shared}),
+	)
+	logger.Debug("msg", zap.String("key", "long value"))
+
+	requireEqual(t, uint64(1), limitsCounter.Load())
+	requireEqual(t, uint64(1), counter.Load())
+	requireEqual(t, uint64(1), shared.Load())
+}
+
+func TestLimitsLineWithContext(t *testing.T) {
+	logger, buffer := testLogger(encoders.WithLimits(encoders.Limits{MaxLine: 100}))
+	logger.With(zap.String("key", strings.Repeat("a", 100))).Debug("msg")
+
+	_, msg := deconstructLogLine(buffer.String())
+	requireEqual(t, "[msg:msg][…(truncated 106B)]", msg)
+}
@@ -0,0 +1,154 @@
+package encoders
+
+import (
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Dedupe selects which field is written when several fields of an entry
+// share the same key.
+type Dedupe int8
+
+const (
+	// KeepAll writes all the fields, even if their keys are repeated.
+	KeepAll Dedupe = iota
+	// KeepFirst writes only the first field added with each key, usually
+	// the one added through the outermost With.
+	KeepFirst
+	// KeepLast writes only the last field added with each key, usually the
+	// one given at the log site.
+	KeepLast
+)
+
+// WithDedupe writes only one of the top-level fields sharing the same key,
+// including the ones added through With. Fields within objects and
+// namespaces are never deduplicated.
+func WithDedupe(d Dedupe) Option {
+	return func(o *options) {
+		o.dedupe = d
+	}
+}
+
+// WithSortedFields writes the top-level fields of each entry sorted by key,
+// after the entry time, level, name, caller and message. Fields within
+// objects are written in the order they were added, and a namespace is
+// sorted as a single field holding all the ones added after it.
+func WithSortedFields() Option {
+	return func(o *options) {
+		o.sortFields = true
+	}
+}
+
+// WithReservedKeys prevents top-level fields from shadowing the keys used
+// for the entry time, level, name, caller, message and stacktrace, by adding
+// the given prefix to their keys:
+//
+//	[ts:2019-04-08T20:21:32.375067Z][level:info][msg:my message][field.msg:user value]
+func WithReservedKeys(prefix string) Option {
+	return func(o *options) {
+		o.reservedPrefix = prefix
+	}
+}
+
+// reorders reports whether top-level fields must be tracked to be reordered
+// or deduplicated.
+func (o *options) reorders() bool {
+	return o.dedupe != KeepAll || o.sortFields
+}
+
+// fieldSpan locates an encoded top-level field within the encoder buffer.
+type fieldSpan struct {
+	key string
+	// sep is where the separator preceding the field starts, and start is
+	// where the field key starts.
+	sep, start int
+}
+
+// trackKey records the position of a top-level field about to be added.
+func (enc *kvEncoder) trackKey(key string) {
+	sep := enc.buf.Len()
+	enc.addElementSeparator()
+	enc.spans = append(enc.spans, fieldSpan{key: key, sep: sep, start: enc.buf.Len()})
+}
+
+// atTopLevel reports whether a key being added belongs to a top-level user
+// field.
+func (enc *kvEncoder) atTopLevel() bool {
+	return !enc.header && enc.depth == 0 && enc.openNamespaces == 0
+}
+
+// isReserved reports whether the key is used for the entry metadata.
+func (enc *kvEncoder) isReserved(key string) bool {
+	switch key {
+	case "":
+		return false
+	case enc.TimeKey, enc.LevelKey, enc.NameKey, enc.CallerKey, enc.MessageKey, enc.StacktraceKey:
+		return true
+	}
+	return false
+}
+
+// addOrderedFields writes into final the fields accumulated by enc followed
+// by the given ones, deduplicated and sorted as configured.
+func (enc *kvEncoder) addOrderedFields(final *kvEncoder, fields []zapcore.Field) {
+	user := enc.Clone().(*kvEncoder)
+	user.errDetail = final.errDetail
+	user.addFields(fields)
+	user.closeOpenNamespaces()
+
+	b := user.buf.Bytes()
+	spans := user.spans
+
+	keep := make([]int, 0, len(spans))
+	switch enc.opts.dedupe {
+	case KeepFirst:
+		seen := make(map[string]bool, len(spans))
+		for i, s := range spans {
+			if !seen[s.key] {
+				seen[s.key] = true
+				keep = append(keep, i)
+			}
+		}
+	case KeepLast:
+		last := make(map[string]int, len(spans))
+		for i, s := range spans {
+			last[s.key] = i
+		}
+		for i, s := range spans {
+			if last[s.key] == i {
+				keep = append(keep, i)
+			}
+		}
+	default:
+		for i := range spans {
+			keep = append(keep, i)
+		}
+	}
+
+	if enc.opts.sortFields {
+		sort.SliceStable(keep, func(i, j int) bool {
+			return spans[keep[i]].key < spans[keep[j]].key
+		})
+	}
+
+	for _, i := range keep {
+		end := len(b)
+		if i+1 < len(spans) {
+			end = spans[i+1].sep
+		}
+
+		if final.tracksLine() {
+			final.addLineField(final.buf.Len(), -1, false)
+		}
+		final.buf.AppendString("][")
+		if final.spaced {
+			final.buf.AppendByte(' ')
+		}
+		final.buf.Write(b[spans[i].start:end])
+	}
+
+	final.truncated = final.truncated || user.truncated
+	user.buf.Free()
+	putKeyValueEncoder(user)
+}
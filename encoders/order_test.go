@@ -0,0 +1,93 @@
+package encoders_test
+
+import (
+	"testing"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"go.uber.org/zap"
+)
+
+func TestFieldOrder(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Options  []encoders.Option
+		With     []zap.Field
+		Fields   []zap.Field
+		Expected string
+	}{
+		{
+			Name:     "Keeps All By Default",
+			With:     []zap.Field{zap.String("request_id", "a"), zap.Int("n", 1)},
+			Fields:   []zap.Field{zap.String("request_id", "b")},
+			Expected: "[msg:my debug message][request_id:a][n:1][request_id:b]",
+		},
+		{
+			Name:     "Keep Last",
+			Options:  []encoders.Option{encoders.WithDedupe(encoders.KeepLast)},
+			With:     []zap.Field{zap.String("request_id", "a"), zap.Int("n", 1)},
+			Fields:   []zap.Field{zap.String("request_id", "b")},
+			Expected: "[msg:my debug message][n:1][request_id:b]",
+		},
+		{
+			Name:     "Keep First",
+			Options:  []encoders.Option{encoders.WithDedupe(encoders.KeepFirst)},
+			With:     []zap.Field{zap.String("request_id", "a"), zap.Int("n", 1)},
+			Fields:   []zap.Field{zap.String("request_id", "b"), zap.Int("m", 2)},
+			Expected: "[msg:my debug message][request_id:a][n:1][m:2]",
+		},
+		{
+			Name:     "Sorted",
+			Options:  []encoders.Option{encoders.WithSortedFields()},
+			With:     []zap.Field{zap.String("c", "1"), zap.String("a", "2")},
+			Fields:   []zap.Field{zap.Strings("b", []string{"x", "y"}), zap.String("a", "3")},
+			Expected: "[msg:my debug message][a:2][a:3][b:[x][y]][c:1]",
+		},
+		{
+			Name:     "Sorted And Deduplicated",
+			Options:  []encoders.Option{encoders.WithSortedFields(), encoders.WithDedupe(encoders.KeepLast)},
+			With:     []zap.Field{zap.String("c", "1"), zap.String("a", "2")},
+			Fields:   []zap.Field{zap.String("b", "4"), zap.String("a", "3")},
+			Expected: "[msg:my debug message][a:3][b:4][c:1]",
+		},
+		{
+			Name:     "Namespaces Are Kept Whole",
+			Options:  []encoders.Option{encoders.WithSortedFields(), encoders.WithDedupe(encoders.KeepLast)},
+			With:     []zap.Field{zap.String("z", "1"), zap.Namespace("ns"), zap.String("z", "2")},
+			Fields:   []zap.Field{zap.String("a", "3")},
+			Expected: "[msg:my debug message][ns:{z:2][a:3}][z:1]",
+		},
+		{
+			Name:     "Reserved Keys",
+			Options:  []encoders.Option{encoders.WithReservedKeys("field.")},
+			With:     []zap.Field{zap.String("level", "high")},
+			Fields:   []zap.Field{zap.String("msg", "shadowed"), zap.Object("obj", user{Name: "msg", Email: "a@b.c", Password: "x"})},
+			Expected: "[msg:my debug message][field.level:high][field.msg:shadowed][obj:{name:msg][email:a@b.c][password:x}]",
+		},
+		{
+			Name: "Reflected Values Are Kept Whole",
+			Options: []encoders.Option{
+				encoders.WithReflection(encoders.Reflection{}),
+				encoders.WithSortedFields(),
+				encoders.WithDedupe(encoders.KeepLast),
+				encoders.WithReservedKeys("field."),
+			},
+			With: []zap.Field{zap.String("b", "1")},
+			Fields: []zap.Field{
+				zap.Reflect("obj", map[string]interface{}{"z": 3, "msg": 4, "b": []int{5}}),
+				zap.String("a", "2"),
+			},
+			Expected: "[msg:my debug message][a:2][b:1][obj:{b:[5]][msg:4][z:3}]",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			logger, buffer := testLogger(tc.Options...)
+			logger.With(tc.With...).Debug("my debug message", tc.Fields...)
+
+			lvl, msg := deconstructLogLine(buffer.String())
+			requireEqual(t, "debug", lvl)
+			requireEqual(t, tc.Expected, msg)
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package encoders
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+)
+
+// DefaultMask is the text written in place of values redacted with Mask,
+// unless Redaction.Mask says otherwise.
+const DefaultMask = "***"
+
+// RedactAction is the action taken on a value matched by a redaction rule.
+type RedactAction int8
+
+const (
+	// Mask replaces the value with the configured mask.
+	Mask RedactAction = iota
+	// Hash replaces the value with its HMAC-SHA256, so equal values can still
+	// be correlated without being disclosed. The value is hashed as it would
+	// be written without redaction, so the same value gets the same hash
+	// wherever it's found.
+	Hash
+	// Drop removes the value. Fields are removed altogether, while matches
+	// within a string value are removed from it.
+	Drop
+)
+
+// KeyRule redacts the value of every field whose key matches Pattern, no
+// matter its type. Pattern is matched case insensitively and may use the
+// syntax of path.Match (e.g. "*token*"), a pattern without wildcards
+// matches only that exact key.
+//
+// Rules apply to keys at any level: top-level fields, nested objects and
+// keys of reflected values.
+type KeyRule struct {
+	Pattern string
+	Action  RedactAction
+}
+
+// ValueRule redacts the parts of string values matching Pattern. When Luhn
+// is set, only matches passing the Luhn checksum are redacted, which avoids
+// masking every long number when looking for card numbers.
+type ValueRule struct {
+	Pattern *regexp.Regexp
+	Luhn    bool
+	Action  RedactAction
+}
+
+// Redaction configures what the encoder redacts and how.
+type Redaction struct {
+	Keys   []KeyRule
+	Values []ValueRule
+
+	// HashKey is the secret used by the Hash action, required if any rule
+	// hashes, as short values hashed without a secret are easily guessed.
+	HashKey []byte
+
+	// Mask is the text used by the Mask action, DefaultMask if empty.
+	Mask string
+}
+
+var (
+	// EmailRule masks email addresses.
+	EmailRule = ValueRule{
+		Pattern: regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+		Action:  Mask,
+	}
+
+	// CardNumberRule masks payment card numbers, with or without separators.
+	CardNumberRule = ValueRule{
+		Pattern: regexp.MustCompile(`\b[0-9](?:[ \-]?[0-9]){12,18}\b`),
+		Luhn:    true,
+		Action:  Mask,
+	}
+
+	// BearerTokenRule masks bearer tokens, as sent in Authorization headers.
+	BearerTokenRule = ValueRule{
+		Pattern: regexp.MustCompile(`(?i)bearer\s+[a-zA-Z0-9\-._~+/]+=*`),
+		Action:  Mask,
+	}
+)
+
+// WithRedaction redacts field values according to the given configuration.
+//
+// Redaction applies consistently to top-level fields, nested objects, arrays
+// and reflected values. Keep in mind value rules run on every string that is
+// encoded, including the message.
+//
+// WithRedaction panics if a rule hashes values but no HashKey is given.
+func WithRedaction(cfg Redaction) Option {
+	if len(cfg.HashKey) == 0 && hashes(cfg) {
+		panic("encoders: redaction rules with the Hash action need a HashKey")
+	}
+
+	r := &redactor{
+		exact:   make(map[string]RedactAction),
+		values:  cfg.Values,
+		hashKey: cfg.HashKey,
+		mask:    cfg.Mask,
+	}
+	if r.mask == "" {
+		r.mask = DefaultMask
+	}
+
+	for _, rule := range cfg.Keys {
+		pattern := strings.ToLower(rule.Pattern)
+		if strings.ContainsAny(pattern, `*?[\`) {
+			r.globs = append(r.globs, KeyRule{Pattern: pattern, Action: rule.Action})
+			continue
+		}
+		r.exact[pattern] = rule.Action
+	}
+
+	return func(o *options) {
+		o.redactor = r
+	}
+}
+
+// hashes reports whether any rule of cfg takes the Hash action.
+func hashes(cfg Redaction) bool {
+	for _, rule := range cfg.Keys {
+		if rule.Action == Hash {
+			return true
+		}
+	}
+	for _, rule := range cfg.Values {
+		if rule.Action == Hash {
+			return true
+		}
+	}
+	return false
+}
+
+// redactor is the compiled form of a Redaction.
+type redactor struct {
+	exact   map[string]RedactAction
+	globs   []KeyRule
+	values  []ValueRule
+	hashKey []byte
+	mask    string
+}
+
+// keyRule returns the action to take on the value of the given key, if any.
+func (r *redactor) keyRule(key string) (RedactAction, bool) {
+	key = strings.ToLower(key)
+	if action, ok := r.exact[key]; ok {
+		return action, true
+	}
+	for _, rule := range r.globs {
+		if ok, _ := path.Match(rule.Pattern, key); ok {
+			return rule.Action, true
+		}
+	}
+	return 0, false
+}
+
+// redactsValues reports whether there are rules applying to string values.
+func (r *redactor) redactsValues() bool {
+	return len(r.values) > 0
+}
+
+// redactString applies all value rules to s.
+func (r *redactor) redactString(s string) string {
+	for _, rule := range r.values {
+		if !rule.Pattern.MatchString(s) {
+			continue
+		}
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.Luhn && !luhnValid(match) {
+				return match
+			}
+			switch rule.Action {
+			case Hash:
+				return r.hashString(match)
+			case Drop:
+				return ""
+			default:
+				return r.mask
+			}
+		})
+	}
+	return s
+}
+
+// hash returns the truncated, hex encoded HMAC-SHA256 of b.
+func (r *redactor) hash(b []byte) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// hashString returns the hash of s as written by the encoder, so that it
+// matches the hash of the same string found in any other value.
+func (r *redactor) hashString(s string) string {
+	tmp := getKeyValueEncoder()
+	tmp.buf = getBufferPool()
+	tmp.safeAddString(s)
+	h := r.hash(tmp.buf.Bytes())
+
+	tmp.buf.Free()
+	putKeyValueEncoder(tmp)
+	return h
+}
+
+// hashRaw returns the hash of an encoded JSON value: strings are hashed as
+// written by the encoder, other values as they're encoded, like reflected
+// values written without redaction.
+func (r *redactor) hashRaw(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return r.hashString(s)
+	}
+	return r.hash(raw)
+}
+
+// redactJSON redacts the JSON value held in buf in place. Only the redacted
+// values change, keys keep their order and repeated keys are kept.
+func (r *redactor) redactJSON(buf *buffer.Buffer) error {
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	dec.UseNumber()
+
+	out := getBufferPool()
+	defer out.Free()
+	if err := r.redactNext(dec, out); err != nil {
+		return err
+	}
+
+	buf.Reset()
+	_, err := buf.Write(out.Bytes())
+	return err
+}
+
+// redactNext copies the next JSON value of dec into out, redacting it.
+func (r *redactor) redactNext(dec *json.Decoder, out *buffer.Buffer) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch val := tok.(type) {
+	case json.Delim:
+		if val == '[' {
+			out.AppendByte('[')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					out.AppendByte(',')
+				}
+				if err := r.redactNext(dec, out); err != nil {
+					return err
+				}
+			}
+			out.AppendByte(']')
+		} else {
+			if err := r.redactObject(dec, out); err != nil {
+				return err
+			}
+		}
+		// Consume the closing delimiter.
+		_, err = dec.Token()
+		return err
+	case string:
+		appendJSONString(out, r.redactString(val))
+	case json.Number:
+		out.AppendString(val.String())
+	case bool:
+		out.AppendBool(val)
+	case nil:
+		out.AppendString("null")
+	}
+	return nil
+}
+
+// redactObject copies the members of the JSON object being read by dec into
+// out, redacting the values of the keys matching a rule.
+func (r *redactor) redactObject(dec *json.Decoder, out *buffer.Buffer) error {
+	out.AppendByte('{')
+	first := true
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+
+		action, redacted := r.keyRule(key)
+		var raw json.RawMessage
+		if redacted {
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			if action == Drop {
+				continue
+			}
+		}
+
+		if !first {
+			out.AppendByte(',')
+		}
+		first = false
+		appendJSONString(out, key)
+		out.AppendByte(':')
+
+		switch {
+		case !redacted:
+			if err := r.redactNext(dec, out); err != nil {
+				return err
+			}
+		case action == Hash:
+			appendJSONString(out, r.hashRaw(raw))
+		default:
+			appendJSONString(out, r.mask)
+		}
+	}
+	out.AppendByte('}')
+	return nil
+}
+
+// appendJSONString appends s encoded as a JSON string, escaped like the
+// reflected values are.
+func appendJSONString(out *buffer.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	out.Write(b)
+}
+
+// keyRule returns the action to take on the value of the given key, if any.
+func (enc *kvEncoder) keyRule(key string) (RedactAction, bool) {
+	if enc.opts.redactor == nil {
+		return 0, false
+	}
+	return enc.opts.redactor.keyRule(key)
+}
+
+// addRedacted adds the key with its redacted value. The value is appended
+// by the given function only if it's needed to compute the hash, without
+// redaction nor limits so that the hash is the one of the whole value.
+func (enc *kvEncoder) addRedacted(key string, action RedactAction, appendValue func(*kvEncoder)) {
+	switch action {
+	case Drop:
+		return
+	case Hash:
+		opts := *enc.opts
+		opts.redactor, opts.limits = nil, nil
+
+		tmp := getKeyValueEncoder()
+		tmp.EncoderConfig = enc.EncoderConfig
+		tmp.opts = &opts
+		tmp.buf = getBufferPool()
+
+		appendValue(tmp)
+		h := enc.opts.redactor.hash(tmp.buf.Bytes())
+
+		tmp.buf.Free()
+		putKeyValueEncoder(tmp)
+
+		enc.addKey(key)
+		enc.addElementSeparator()
+		enc.safeAddString(h)
+	default:
+		enc.addKey(key)
+		enc.addElementSeparator()
+		enc.safeAddString(enc.opts.redactor.mask)
+	}
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
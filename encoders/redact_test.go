@@ -0,0 +1,132 @@
+package encoders_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type user struct {
+	Name     string
+	Email    string
+	Password string
+}
+
+func (u user) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	enc.AddString("email", u.Email)
+	enc.AddString("password", u.Password)
+	return nil
+}
+
+func TestRedaction(t *testing.T) {
+	redaction := encoders.Redaction{
+		Keys: []encoders.KeyRule{
+			{Pattern: "password", Action: encoders.Drop},
+			{Pattern: "authorization", Action: encoders.Mask},
+			{Pattern: "*_token", Action: encoders.Mask},
+			{Pattern: "user_id", Action: encoders.Hash},
+		},
+		Values: []encoders.ValueRule{
+			encoders.EmailRule,
+			encoders.CardNumberRule,
+			{Pattern: regexp.MustCompile(`secret-[a-z]+`), Action: encoders.Drop},
+		},
+		HashKey: []byte("my hash key"),
+	}
+
+	tt := []struct {
+		Name     string
+		Fields   []zap.Field
+		Expected string
+	}{
+		{
+			Name:     "Key Rules",
+			Fields:   []zap.Field{zap.String("Password", "1234"), zap.String("access_token", "abcd"), zap.Int64("count", 3)},
+			Expected: "[msg:my debug message][access_token:***][count:3]",
+		},
+		{
+			Name:     "Key Rules Apply To Any Type",
+			Fields:   []zap.Field{zap.Int64("authorization", 1234), zap.Strings("refresh_token", []string{"a", "b"})},
+			Expected: "[msg:my debug message][authorization:***][refresh_token:***]",
+		},
+		{
+			Name:     "Hash Is Stable",
+			Fields:   []zap.Field{zap.String("user_id", "1234"), zap.String("other", "1234")},
+			Expected: "[msg:my debug message][user_id:2a32555a7683a55f][other:1234]",
+		},
+		{
+			Name:     "Hash Ignores Value Rules",
+			Fields:   []zap.Field{zap.String("user_id", "a@b.com"), zap.Reflect("data", map[string]string{"user_id": "c@d.com"})},
+			Expected: `[msg:my debug message][user_id:464c880abd09fd9d][data:{"user_id":"c9e952a9ea2699f9"}]`,
+		},
+		{
+			Name:     "Hash Is The Same In Reflected Values",
+			Fields:   []zap.Field{zap.String("user_id", "1234"), zap.Reflect("data", map[string]string{"user_id": "1234"})},
+			Expected: `[msg:my debug message][user_id:2a32555a7683a55f][data:{"user_id":"2a32555a7683a55f"}]`,
+		},
+		{
+			Name:     "Value Rules",
+			Fields:   []zap.Field{zap.String("body", "contact john@example.com paying with 4111 1111 1111 1111, not 1234 5678 9012 3456")},
+			Expected: "[msg:my debug message][body:contact *** paying with ***, not 1234 5678 9012 3456]",
+		},
+		{
+			Name:     "Value Rules On Byte Strings And Arrays",
+			Fields:   []zap.Field{zap.ByteString("raw", []byte("secret-abc key")), zap.Strings("emails", []string{"a@b.com", "none"})},
+			Expected: "[msg:my debug message][raw: key][emails:[***][none]]",
+		},
+		{
+			Name:     "Nested Objects",
+			Fields:   []zap.Field{zap.Object("user", user{Name: "john", Email: "john@example.com", Password: "1234"})},
+			Expected: "[msg:my debug message][user:{name:john][email:***}]",
+		},
+		{
+			Name: "Reflected Values",
+			Fields: []zap.Field{zap.Reflect("headers", http.Header{
+				"Authorization": []string{"Bearer abcd"},
+				"From":          []string{"john@example.com"},
+				"Accept":        []string{"*/*"},
+			})},
+			Expected: `[msg:my debug message][headers:{"Accept":["*/*"],"Authorization":"***","From":["***"]}]`,
+		},
+		{
+			Name: "Reflected Values Keep Their Order",
+			Fields: []zap.Field{
+				zap.Reflect("user", struct {
+					Name     string `json:"name"`
+					Password string `json:"password"`
+					Email    string `json:"email"`
+				}{"john", "1234", "john@example.com"}),
+				zap.Reflect("raw", json.RawMessage(`{"b":1,"a":2,"a":{"z":true,"y":null}}`)),
+			},
+			Expected: `[msg:my debug message][user:{"name":"john","email":"***"}][raw:{"b":1,"a":2,"a":{"z":true,"y":null}}]`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			logger, buffer := testLogger(encoders.WithRedaction(redaction))
+			logger.Debug("my debug message", tc.Fields...)
+
+			lvl, msg := deconstructLogLine(buffer.String())
+			requireEqual(t, "debug", lvl)
+			requireEqual(t, tc.Expected, msg)
+		})
+	}
+}
+
+func TestRedactionHashKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected WithRedaction to panic without HashKey")
+		}
+	}()
+	encoders.WithRedaction(encoders.Redaction{
+		Keys: []encoders.KeyRule{{Pattern: "user_id", Action: encoders.Hash}},
+	})
+}
@@ -0,0 +1,469 @@
+package encoders
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultMaxDepth is the nesting limit used when Reflection.MaxDepth is 0.
+	DefaultMaxDepth = 10
+
+	// DefaultMaxElements is the collection size limit used when
+	// Reflection.MaxElements is 0.
+	DefaultMaxElements = 100
+)
+
+// Reflection configures the native encoding of reflected values.
+type Reflection struct {
+	// MaxDepth limits how deep nested structs, maps, slices and pointers are
+	// walked. Deeper values are replaced by "…".
+	MaxDepth int
+
+	// MaxElements limits the number of elements written for each map, slice
+	// and array. The rest are summarized as a last "…(N more)" element.
+	MaxElements int
+
+	// JSONFallback encodes with encoding/json the values implementing
+	// json.Marshaler, as well as the ones that can't be walked (channels,
+	// functions, ...). Otherwise the former are walked as any other value and
+	// the latter are written as their type name.
+	JSONFallback bool
+}
+
+// WithReflection encodes reflected values (such as the ones given to
+// zap.Reflect or zap.Any) by walking them directly into the key value format,
+// instead of embedding their JSON encoding.
+//
+// Structs and maps are written as objects, with map keys sorted so the output
+// is deterministic, while slices and arrays are written as arrays. Struct
+// fields honor their json tags, and values implementing fmt.Stringer or error
+// are written as strings. Cycles are detected and written as "<cycle>".
+func WithReflection(cfg Reflection) Option {
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = DefaultMaxDepth
+	}
+	if cfg.MaxElements <= 0 {
+		cfg.MaxElements = DefaultMaxElements
+	}
+
+	return func(o *options) {
+		o.reflection = &cfg
+	}
+}
+
+var (
+	_jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	_objectMarshalerType = reflect.TypeOf((*zapcore.ObjectMarshaler)(nil)).Elem()
+	_arrayMarshalerType  = reflect.TypeOf((*zapcore.ArrayMarshaler)(nil)).Elem()
+	_timeType            = reflect.TypeOf(time.Time{})
+	_durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// reflectWalker writes reflected values into a key value encoder.
+type reflectWalker struct {
+	enc *kvEncoder
+	cfg *Reflection
+
+	// limits taking into account both the configuration and the encoder limits
+	maxDepth    int
+	maxElements int
+
+	// visiting holds the pointers being walked, to detect cycles.
+	visiting map[uintptr]struct{}
+}
+
+// appendReflectedValue writes obj using the native reflection encoding.
+func (enc *kvEncoder) appendReflectedValue(obj interface{}) error {
+	enc.nestLineField()
+	w := reflectWalker{
+		enc:         enc,
+		cfg:         enc.opts.reflection,
+		maxDepth:    enc.opts.reflection.MaxDepth,
+		maxElements: enc.opts.reflection.MaxElements,
+	}
+
+	l := enc.opts.limit()
+	if l.MaxDepth > 0 && l.MaxDepth-enc.depth < w.maxDepth {
+		w.maxDepth = l.MaxDepth - enc.depth
+	}
+	if l.MaxElements > 0 && l.MaxElements < w.maxElements {
+		w.maxElements = l.MaxElements
+	}
+
+	return w.walk(reflect.ValueOf(obj), 0)
+}
+
+func (w *reflectWalker) walk(v reflect.Value, depth int) error {
+	enc := w.enc
+
+	if !v.IsValid() {
+		enc.AppendString("<nil>")
+		return nil
+	}
+	if depth > w.maxDepth {
+		enc.truncated = true
+		enc.AppendString("…")
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			enc.AppendString("<nil>")
+			return nil
+		}
+	}
+	if v.Kind() == reflect.Interface {
+		return w.walk(v.Elem(), depth)
+	}
+
+	if done, err := w.walkSpecial(v); done {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		enc.AppendBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		enc.AppendInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		enc.AppendUint64(v.Uint())
+	case reflect.Float32:
+		enc.AppendFloat32(float32(v.Float()))
+	case reflect.Float64:
+		enc.AppendFloat64(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		enc.AppendComplex128(v.Complex())
+	case reflect.String:
+		enc.AppendString(v.String())
+	case reflect.Ptr:
+		return w.walkPointer(v, depth)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			enc.AppendByteString(v.Bytes())
+			return nil
+		}
+		return w.walkPointer(v, depth)
+	case reflect.Array:
+		return w.walkArray(v, depth)
+	case reflect.Map:
+		return w.walkPointer(v, depth)
+	case reflect.Struct:
+		return w.walkStruct(v, depth)
+	default:
+		if w.cfg.JSONFallback {
+			return w.walkJSON(v)
+		}
+		enc.AppendString(fmt.Sprintf("<%s>", v.Type()))
+	}
+	return nil
+}
+
+// walkSpecial writes the values whose type has its own representation,
+// returning whether v was written.
+func (w *reflectWalker) walkSpecial(v reflect.Value) (bool, error) {
+	t := v.Type()
+	switch t {
+	case _timeType:
+		w.enc.AppendTime(v.Interface().(time.Time))
+		return true, nil
+	case _durationType:
+		w.enc.AppendDuration(time.Duration(v.Int()))
+		return true, nil
+	}
+
+	if !v.CanInterface() {
+		return false, nil
+	}
+
+	switch {
+	case t.Implements(_objectMarshalerType):
+		return true, w.enc.AppendObject(v.Interface().(zapcore.ObjectMarshaler))
+	case t.Implements(_arrayMarshalerType):
+		return true, w.enc.AppendArray(v.Interface().(zapcore.ArrayMarshaler))
+	case w.cfg.JSONFallback && t.Implements(_jsonMarshalerType):
+		return true, w.walkJSON(v)
+	}
+
+	switch val := v.Interface().(type) {
+	case error:
+		w.enc.AppendString(val.Error())
+		return true, nil
+	case fmt.Stringer:
+		w.enc.AppendString(val.String())
+		return true, nil
+	}
+	return false, nil
+}
+
+// walkPointer walks the values that may be referenced from themselves,
+// detecting cycles.
+func (w *reflectWalker) walkPointer(v reflect.Value, depth int) error {
+	ptr := v.Pointer()
+	if _, ok := w.visiting[ptr]; ok {
+		w.enc.AppendString("<cycle>")
+		return nil
+	}
+	if w.visiting == nil {
+		w.visiting = make(map[uintptr]struct{})
+	}
+	w.visiting[ptr] = struct{}{}
+	defer delete(w.visiting, ptr)
+
+	switch v.Kind() {
+	case reflect.Map:
+		return w.walkMap(v, depth)
+	case reflect.Slice:
+		return w.walkArray(v, depth)
+	default:
+		return w.walk(v.Elem(), depth+1)
+	}
+}
+
+func (w *reflectWalker) walkArray(v reflect.Value, depth int) error {
+	enc := w.enc
+	enc.addElementSeparator()
+	enc.buf.AppendByte('[')
+	enc.depth++
+
+	var err error
+	n := v.Len()
+	for i := 0; i < n && i < w.maxElements && err == nil; i++ {
+		err = w.walk(v.Index(i), depth+1)
+	}
+	if err == nil && n > w.maxElements {
+		enc.truncated = true
+		enc.AppendString(fmt.Sprintf("…(%d more)", n-w.maxElements))
+	}
+
+	enc.depth--
+	enc.buf.AppendByte(']')
+	return err
+}
+
+func (w *reflectWalker) walkMap(v reflect.Value, depth int) error {
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		entries = append(entries, entry{key: mapKey(k), val: v.MapIndex(k)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	enc := w.enc
+	enc.addElementSeparator()
+	enc.buf.AppendByte('{')
+	enc.depth++
+
+	var err error
+	for i, e := range entries {
+		if i == w.maxElements {
+			enc.truncated = true
+			enc.AddString("…", fmt.Sprintf("%d more", len(entries)-i))
+			break
+		}
+		if err = w.walkField(e.key, e.val, depth); err != nil {
+			break
+		}
+	}
+
+	enc.depth--
+	enc.buf.AppendByte('}')
+	return err
+}
+
+func (w *reflectWalker) walkStruct(v reflect.Value, depth int) error {
+	enc := w.enc
+	enc.addElementSeparator()
+	enc.buf.AppendByte('{')
+	enc.depth++
+
+	var err error
+	for _, f := range cachedStructFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		if err = w.walkField(f.name, fv, depth); err != nil {
+			break
+		}
+	}
+
+	enc.depth--
+	enc.buf.AppendByte('}')
+	return err
+}
+
+// walkField writes a keyed value within an object, honoring redaction rules.
+func (w *reflectWalker) walkField(key string, v reflect.Value, depth int) error {
+	enc := w.enc
+	if action, ok := enc.keyRule(key); ok {
+		var err error
+		enc.addRedacted(key, action, func(e *kvEncoder) {
+			sub := *w
+			sub.enc = e
+			err = sub.walk(v, depth+1)
+		})
+		return err
+	}
+
+	enc.addKey(key)
+	return w.walk(v, depth+1)
+}
+
+// walkJSON writes v using its JSON encoding.
+func (w *reflectWalker) walkJSON(v reflect.Value) error {
+	if err := w.enc.encodeReflected(v.Interface()); err != nil {
+		return err
+	}
+	w.enc.addElementSeparator()
+	_, err := w.enc.buf.Write(w.enc.reflectBuf.Bytes())
+	return err
+}
+
+// mapKey returns the string representation of a map key.
+func mapKey(k reflect.Value) string {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10)
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// structField describes an encoded struct field.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var _structFieldsCache sync.Map // map[reflect.Type][]structField
+
+// cachedStructFields returns the encoded fields of the struct type t.
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := _structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields, _ := _structFieldsCache.LoadOrStore(t, typeFields(t))
+	return fields.([]structField)
+}
+
+// typeFields lists the fields of the struct type t following encoding/json
+// rules: unexported and "-" fields are skipped and the fields of embedded
+// structs are promoted, unless hidden by a shallower field with the same name.
+func typeFields(t reflect.Type) []structField {
+	type candidate struct {
+		structField
+		depth int
+	}
+
+	var candidates []candidate
+	var visit func(t reflect.Type, index []int)
+	visit = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts := tag, ""
+			if idx := strings.Index(tag, ","); idx >= 0 {
+				name, opts = tag[:idx], tag[idx+1:]
+			}
+
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				visit(ft, append(index[:len(index):len(index)], i))
+				continue
+			}
+			if sf.PkgPath != "" {
+				continue
+			}
+
+			if name == "" {
+				name = sf.Name
+			}
+			candidates = append(candidates, candidate{
+				structField: structField{
+					name:      name,
+					index:     append(index[:len(index):len(index)], i),
+					omitEmpty: strings.Contains(opts, "omitempty"),
+				},
+				depth: len(index),
+			})
+		}
+	}
+	visit(t, nil)
+
+	shallowest := make(map[string]int)
+	for _, c := range candidates {
+		if d, ok := shallowest[c.name]; !ok || c.depth < d {
+			shallowest[c.name] = c.depth
+		}
+	}
+
+	fields := make([]structField, 0, len(candidates))
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if c.depth != shallowest[c.name] || seen[c.name] {
+			continue
+		}
+		seen[c.name] = true
+		fields = append(fields, c.structField)
+	}
+	return fields
+}
+
+// fieldByIndex returns the nested field of v at index, or false if it's
+// unreachable through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether v is empty as defined by encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package encoders_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"go.uber.org/zap"
+)
+
+type address struct {
+	Street string `json:"street"`
+	Number int    `json:"number,omitempty"`
+}
+
+type person struct {
+	address
+	Name     string            `json:"name"`
+	Nickname string            `json:"nickname,omitempty"`
+	Ignored  string            `json:"-"`
+	Tags     map[string]string `json:"tags"`
+	Friend   *person           `json:"friend"`
+	private  string
+}
+
+type jsonValue struct{}
+
+func (jsonValue) MarshalJSON() ([]byte, error) {
+	return []byte(`{"custom":true}`), nil
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestReflection(t *testing.T) {
+	cyclic := &node{Name: "a"}
+	cyclic.Next = &node{Name: "b", Next: cyclic}
+
+	tt := []struct {
+		Name     string
+		Config   encoders.Reflection
+		Field    zap.Field
+		Expected string
+	}{
+		{
+			Name:     "Map With Sorted Keys",
+			Field:    zap.Reflect("headers", http.Header{"B": {"2"}, "A": {"1", "3"}}),
+			Expected: "[msg:my debug message][headers:{A:[1][3]][B:[2]}]",
+		},
+		{
+			Name: "Struct Honors JSON Tags",
+			Field: zap.Any("person", person{
+				address: address{Street: "Main"},
+				Name:    "john",
+				Ignored: "ignored",
+				Tags:    map[string]string{"role": "admin"},
+				Friend:  &person{address: address{Street: "Side", Number: 2}, Name: "jane"},
+				private: "private",
+			}),
+			Expected: "[msg:my debug message][person:{street:Main][name:john][tags:{role:admin}][friend:{street:Side][number:2][name:jane][tags:<nil>][friend:<nil>}}]",
+		},
+		{
+			Name:     "Stringers And Errors",
+			Field:    zap.Reflect("values", []interface{}{time.Second, errors.New("failure"), nil, 1.5}),
+			Expected: "[msg:my debug message][values:[1][failure][<nil>][1.5]]",
+		},
+		{
+			Name:     "Cycles",
+			Field:    zap.Reflect("node", cyclic),
+			Expected: "[msg:my debug message][node:{Name:a][Next:{Name:b][Next:<cycle>}}]",
+		},
+		{
+			Name:     "Depth Limit",
+			Config:   encoders.Reflection{MaxDepth: 2},
+			Field:    zap.Reflect("nested", [][][]int{{{1}}}),
+			Expected: "[msg:my debug message][nested:[[[…]]]]",
+		},
+		{
+			Name:     "Elements Limit",
+			Config:   encoders.Reflection{MaxElements: 2},
+			Field:    zap.Reflect("numbers", []int{1, 2, 3, 4}),
+			Expected: "[msg:my debug message][numbers:[1][2][…(2 more)]]",
+		},
+		{
+			Name:     "Unsupported Types",
+			Field:    zap.Reflect("values", []interface{}{make(chan int), jsonValue{}}),
+			Expected: "[msg:my debug message][values:[<chan int>][{}]]",
+		},
+		{
+			Name:     "JSON Fallback",
+			Config:   encoders.Reflection{JSONFallback: true},
+			Field:    zap.Reflect("values", []interface{}{"a", jsonValue{}}),
+			Expected: `[msg:my debug message][values:[a][{"custom":true}]]`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			logger, buffer := testLogger(encoders.WithReflection(tc.Config))
+			logger.Debug("my debug message", tc.Field)
+
+			lvl, msg := deconstructLogLine(buffer.String())
+			requireEqual(t, "debug", lvl)
+			requireEqual(t, tc.Expected, msg)
+		})
+	}
+}
+
+func TestReflectionRedaction(t *testing.T) {
+	logger, buffer := testLogger(
+		encoders.WithReflection(encoders.Reflection{}),
+		encoders.WithRedaction(encoders.Redaction{
+			Keys:   []encoders.KeyRule{{Pattern: "authorization", Action: encoders.Mask}},
+			Values: []encoders.ValueRule{encoders.EmailRule},
+		}),
+	)
+	logger.Debug("my debug message", zap.Reflect("headers", http.Header{
+		"Authorization": {"Bearer abcd"},
+		"From":          {"john@example.com"},
+	}))
+
+	_, msg := deconstructLogLine(buffer.String())
+	requireEqual(t, "[msg:my debug message][headers:{Authorization:***][From:[***]}]", msg)
+}
@@ -0,0 +1,248 @@
+//go:build go1.18
+// +build go1.18
+
+package fields
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Slice returns a field logging values as an array, each element with the
+// encoder method of its type, as zap.Any would for a single value:
+//
+//	fields.Slice("ids", []int{1, 2, 3})        // [ids:[1][2][3]]
+//	fields.Slice("hosts", []string{"a", "b"})  // [hosts:[a][b]]
+//
+// Unlike zap.Any or zap.Reflect on a slice, values aren't encoded as JSON
+// with reflection, so they render natively with the key value encoder.
+// Only values of types zap has no encoder method for, nor implementing
+// zapcore.ObjectMarshaler, error or fmt.Stringer, are reflected.
+func Slice[T any](key string, values []T) zap.Field {
+	return zap.Array(key, sliceMarshaler[T](values))
+}
+
+type sliceMarshaler[T any] []T
+
+func (s sliceMarshaler[T]) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, v := range s {
+		if err := appendValue(enc, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ordered is the constraint of the keys of the maps given to Map, which are
+// written sorted.
+type Ordered interface {
+	~string | ~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Map returns a field logging a map as an object, sorted by key so that the
+// same map is always written the same, with NaN keys first as sort.Float64s
+// does. Values are encoded as they are by Slice:
+//
+//	fields.Map("counts", map[string]int{"b": 2, "a": 1})  // [counts:{a:1][b:2}]
+func Map[K Ordered, V any](key string, m map[K]V) zap.Field {
+	return zap.Object(key, mapMarshaler[K, V](m))
+}
+
+type mapMarshaler[K Ordered, V any] map[K]V
+
+func (m mapMarshaler[K, V]) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	// Entries are sorted rather than keys, as the values of NaN keys can't
+	// be looked up.
+	type entry struct {
+		key   K
+		value V
+	}
+	entries := make([]entry, 0, len(m))
+	for k, v := range m {
+		entries = append(entries, entry{k, v})
+	}
+	sort.Slice(entries, func(i, j int) bool { return less(entries[i].key, entries[j].key) })
+
+	for _, e := range entries {
+		if err := addValue(enc, keyString(e.key), e.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// less orders map keys, with NaN floats, the only keys not equal to
+// themselves, before any other.
+func less[K Ordered](a, b K) bool {
+	return a < b || a != a && b == b
+}
+
+// keyString returns the text of a map key.
+func keyString(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+// Stringers returns a field logging values as an array of the strings
+// returned by their String method, or <nil> for nil pointers.
+func Stringers[T fmt.Stringer](key string, values []T) zap.Field {
+	return zap.Array(key, stringers[T](values))
+}
+
+type stringers[T fmt.Stringer] []T
+
+func (s stringers[T]) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, v := range s {
+		if isNilPointer(v) {
+			enc.AppendString(nilText)
+			continue
+		}
+		enc.AppendString(v.String())
+	}
+	return nil
+}
+
+// nilText is written for nil pointers, whose methods may not expect them, as
+// fmt does.
+const nilText = "<nil>"
+
+// isNilPointer reports whether v is a nil pointer.
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// appendValue appends a value with the encoder method of its type, trying
+// them in the order zap.Any does.
+func appendValue(enc zapcore.ArrayEncoder, value interface{}) error {
+	if isNilPointer(value) {
+		enc.AppendString(nilText)
+		return nil
+	}
+
+	switch v := value.(type) {
+	case zapcore.ObjectMarshaler:
+		return enc.AppendObject(v)
+	case zapcore.ArrayMarshaler:
+		return enc.AppendArray(v)
+	case bool:
+		enc.AppendBool(v)
+	case complex128:
+		enc.AppendComplex128(v)
+	case complex64:
+		enc.AppendComplex64(v)
+	case float64:
+		enc.AppendFloat64(v)
+	case float32:
+		enc.AppendFloat32(v)
+	case int:
+		enc.AppendInt(v)
+	case int64:
+		enc.AppendInt64(v)
+	case int32:
+		enc.AppendInt32(v)
+	case int16:
+		enc.AppendInt16(v)
+	case int8:
+		enc.AppendInt8(v)
+	case string:
+		enc.AppendString(v)
+	case uint:
+		enc.AppendUint(v)
+	case uint64:
+		enc.AppendUint64(v)
+	case uint32:
+		enc.AppendUint32(v)
+	case uint16:
+		enc.AppendUint16(v)
+	case uint8:
+		enc.AppendUint8(v)
+	case uintptr:
+		enc.AppendUintptr(v)
+	case []byte:
+		enc.AppendByteString(v)
+	case time.Time:
+		enc.AppendTime(v)
+	case time.Duration:
+		enc.AppendDuration(v)
+	case error:
+		enc.AppendString(v.Error())
+	case fmt.Stringer:
+		enc.AppendString(v.String())
+	default:
+		return enc.AppendReflected(v)
+	}
+	return nil
+}
+
+// addValue adds a value with the encoder method of its type, as appendValue
+// does.
+func addValue(enc zapcore.ObjectEncoder, key string, value interface{}) error {
+	if isNilPointer(value) {
+		enc.AddString(key, nilText)
+		return nil
+	}
+
+	switch v := value.(type) {
+	case zapcore.ObjectMarshaler:
+		return enc.AddObject(key, v)
+	case zapcore.ArrayMarshaler:
+		return enc.AddArray(key, v)
+	case bool:
+		enc.AddBool(key, v)
+	case complex128:
+		enc.AddComplex128(key, v)
+	case complex64:
+		enc.AddComplex64(key, v)
+	case float64:
+		enc.AddFloat64(key, v)
+	case float32:
+		enc.AddFloat32(key, v)
+	case int:
+		enc.AddInt(key, v)
+	case int64:
+		enc.AddInt64(key, v)
+	case int32:
+		enc.AddInt32(key, v)
+	case int16:
+		enc.AddInt16(key, v)
+	case int8:
+		enc.AddInt8(key, v)
+	case string:
+		enc.AddString(key, v)
+	case uint:
+		enc.AddUint(key, v)
+	case uint64:
+		enc.AddUint64(key, v)
+	case uint32:
+		enc.AddUint32(key, v)
+	case uint16:
+		enc.AddUint16(key, v)
+	case uint8:
+		enc.AddUint8(key, v)
+	case uintptr:
+		enc.AddUintptr(key, v)
+	case []byte:
+		enc.AddByteString(key, v)
+	case time.Time:
+		enc.AddTime(key, v)
+	case time.Duration:
+		enc.AddDuration(key, v)
+	case error:
+		enc.AddString(key, v.Error())
+	case fmt.Stringer:
+		enc.AddString(key, v.String())
+	default:
+		return enc.AddReflected(key, v)
+	}
+	return nil
+}
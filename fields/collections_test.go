@@ -0,0 +1,161 @@
+//go:build go1.18
+// +build go1.18
+
+package fields_test
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/fields"
+	"go.uber.org/zap/zapcore"
+)
+
+type point struct{ X, Y int }
+
+func (p point) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("x", p.X)
+	enc.AddInt("y", p.Y)
+	return nil
+}
+
+type status int
+
+func (s status) String() string {
+	if s == 0 {
+		return "ok"
+	}
+	return "failed"
+}
+
+func TestCollections(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Field    zapcore.Field
+		Expected string
+	}{
+		{
+			Name:     "Slice Of Ints",
+			Field:    fields.Slice("ids", []int{1, 2, 3}),
+			Expected: `[ids:[1][2][3]]`,
+		},
+		{
+			Name:     "Slice Of Strings",
+			Field:    fields.Slice("hosts", []string{"a", "b c"}),
+			Expected: `[hosts:[a][b c]]`,
+		},
+		{
+			Name:     "Empty Slice",
+			Field:    fields.Slice("ids", []int(nil)),
+			Expected: `[ids:[]]`,
+		},
+		{
+			Name:     "Slice Of Durations",
+			Field:    fields.Slice("retries", []time.Duration{time.Second, 1500 * time.Millisecond}),
+			Expected: `[retries:[1][1.5]]`,
+		},
+		{
+			Name:     "Slice Of Marshalers",
+			Field:    fields.Slice("points", []point{{1, 2}, {3, 4}}),
+			Expected: `[points:[{x:1][y:2}][{x:3][y:4}]]`,
+		},
+		{
+			Name:     "Slice Of Errors",
+			Field:    fields.Slice("errors", []error{errors.New("timeout"), errors.New("refused")}),
+			Expected: `[errors:[timeout][refused]]`,
+		},
+		{
+			Name:     "Slice Of Slices",
+			Field:    fields.Slice("matrix", [][]int{{1, 2}, {3}}),
+			Expected: `[matrix:[[1,2]][[3]]]`,
+		},
+		{
+			Name:     "Map Sorted By Key",
+			Field:    fields.Map("counts", map[string]int{"b": 2, "c": 3, "a": 1}),
+			Expected: `[counts:{a:1][b:2][c:3}]`,
+		},
+		{
+			Name:     "Map With Int Keys",
+			Field:    fields.Map("codes", map[int]string{404: "not found", 200: "ok", 50: "x"}),
+			Expected: `[codes:{50:x][200:ok][404:not found}]`,
+		},
+		{
+			Name:     "Map Of Stringers",
+			Field:    fields.Map("statuses", map[string]status{"db": 0, "cache": 1}),
+			Expected: `[statuses:{cache:failed][db:ok}]`,
+		},
+		{
+			Name:     "Slice Of Nil Pointers",
+			Field:    fields.Slice("errors", []*os.PathError{{Op: "open", Path: "a", Err: os.ErrNotExist}, nil}),
+			Expected: `[errors:[open a: file does not exist][<nil>]]`,
+		},
+		{
+			Name:     "Map With NaN Keys",
+			Field:    fields.Map("ratios", map[float64]string{1: "one", math.NaN(): "nan", -1: "minus one"}),
+			Expected: `[ratios:{NaN:nan][-1:minus one][1:one}]`,
+		},
+		{
+			Name:     "Map Of Nil Pointers",
+			Field:    fields.Map("urls", map[string]*url.URL{"home": {Scheme: "https", Host: "example.com"}, "next": nil}),
+			Expected: `[urls:{home:https://example.com][next:<nil>}]`,
+		},
+		{
+			Name:     "Stringers With Nil Pointers",
+			Field:    fields.Stringers("urls", []*url.URL{nil, {Scheme: "https", Host: "example.com"}}),
+			Expected: `[urls:[<nil>][https://example.com]]`,
+		},
+		{
+			Name:     "Stringers",
+			Field:    fields.Stringers("addrs", []net.IP{net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2)}),
+			Expected: `[addrs:[10.0.0.1][10.0.0.2]]`,
+		},
+	}
+
+	cfg := log.NewProductionEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.LevelKey = ""
+	cfg.CallerKey = ""
+	cfg.MessageKey = ""
+	enc := encoders.NewKeyValueEncoder(cfg)
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{tc.Field})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.TrimSpace(buf.String()); got != tc.Expected {
+				t.Fatalf("expected %s, got %s", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestMapDeterministic(t *testing.T) {
+	m := make(map[string]int)
+	for i, k := range []string{"e", "d", "c", "b", "a", "f", "g", "h"} {
+		m[k] = i
+	}
+	enc := encoders.NewKeyValueEncoder(zapcore.EncoderConfig{})
+	first, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{fields.Map("m", m)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{fields.Map("m", m)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != first.String() {
+			t.Fatalf("expected %s, got %s", first, buf)
+		}
+	}
+}
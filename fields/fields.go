@@ -0,0 +1,14 @@
+// Package fields holds typed constructors for the well-known keys of this
+// module's logs, so that they're logged under the same key and with the same
+// type everywhere:
+//
+//	log.Info(ctx, "request handled", fields.RequestID(id), fields.HTTPStatus(200))
+//
+// The constructors are generated from schema.json, which the Schema variable
+// holds, for schema.NewValidator to check the fields logged against it.
+//
+// Slice, Map and Stringers log collections of any key as arrays and objects,
+// written natively by the key value encoder rather than reflected as JSON.
+package fields
+
+//go:generate go run github.com/emiguens/zapfmt/cmd/fieldgen -o keys.go schema.json
@@ -0,0 +1,61 @@
+package fields_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/emiguens/zapfmt/fields"
+	"github.com/emiguens/zapfmt/schema"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestGenerated checks that keys.go was generated from the current schema.
+func TestGenerated(t *testing.T) {
+	f, err := os.Open("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s, err := schema.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := schema.Generate(s, "fields", "schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile("keys.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Fatal("keys.go is out of date, run go generate")
+	}
+
+	if len(fields.Schema.Fields()) != len(s.Fields()) {
+		t.Fatal("Schema doesn't hold every key of schema.json")
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	for _, f := range []zapcore.Field{
+		fields.RequestID("abc"),
+		fields.TraceID("abc"),
+		fields.SpanID("abc"),
+		fields.UserID("abc"),
+		fields.HTTPMethod("GET"),
+		fields.HTTPPath("/"),
+		fields.HTTPStatus(200),
+		fields.RemoteAddr("127.0.0.1:8080"),
+		fields.Duration(time.Second),
+		fields.Attempt(1),
+	} {
+		if err := fields.Schema.Check(f, true); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Code generated by fieldgen from schema.json. DO NOT EDIT.
+
+package fields
+
+import (
+	"time"
+
+	"github.com/emiguens/zapfmt/schema"
+	"go.uber.org/zap"
+)
+
+// Keys of the schema.
+const (
+	RequestIDKey  = "request_id"
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	UserIDKey     = "user_id"
+	HTTPMethodKey = "http.method"
+	HTTPPathKey   = "http.path"
+	HTTPStatusKey = "http.status"
+	RemoteAddrKey = "remote_addr"
+	DurationKey   = "duration"
+	AttemptKey    = "attempt"
+)
+
+// RequestID returns the request_id field, the ID of a request across
+// services, as given by the X-Request-Id header.
+func RequestID(v string) zap.Field {
+	return zap.String(RequestIDKey, v)
+}
+
+// TraceID returns the trace_id field, the ID of the distributed trace the
+// entry was logged in.
+func TraceID(v string) zap.Field {
+	return zap.String(TraceIDKey, v)
+}
+
+// SpanID returns the span_id field, the ID of the span of the trace the
+// entry was logged in.
+func SpanID(v string) zap.Field {
+	return zap.String(SpanIDKey, v)
+}
+
+// UserID returns the user_id field, the ID of the user a request was made
+// for.
+func UserID(v string) zap.Field {
+	return zap.String(UserIDKey, v)
+}
+
+// HTTPMethod returns the http.method field, the method of an HTTP request.
+func HTTPMethod(v string) zap.Field {
+	return zap.String(HTTPMethodKey, v)
+}
+
+// HTTPPath returns the http.path field, the path of the URL of an HTTP
+// request, without its query.
+func HTTPPath(v string) zap.Field {
+	return zap.String(HTTPPathKey, v)
+}
+
+// HTTPStatus returns the http.status field, the status code of an HTTP
+// response.
+func HTTPStatus(v int) zap.Field {
+	return zap.Int(HTTPStatusKey, v)
+}
+
+// RemoteAddr returns the remote_addr field, the network address of the
+// client that sent a request.
+func RemoteAddr(v string) zap.Field {
+	return zap.String(RemoteAddrKey, v)
+}
+
+// Duration returns the duration field, the time an operation took.
+func Duration(v time.Duration) zap.Field {
+	return zap.Duration(DurationKey, v)
+}
+
+// Attempt returns the attempt field, the number of the attempt of an
+// operation that is retried, from 1.
+func Attempt(v int) zap.Field {
+	return zap.Int(AttemptKey, v)
+}
+
+// Schema is the schema the constructors were generated from, for
+// schema.NewValidator.
+var Schema = schema.MustNew([]schema.Field{
+	{Key: "request_id", Type: schema.String, Name: "RequestID", Aliases: []string{"requestId", "requestID", "reqId", "reqID", "req_id", "request-id"}, Doc: "the ID of a request across services, as given by the X-Request-Id header."},
+	{Key: "trace_id", Type: schema.String, Name: "TraceID", Aliases: []string{"traceId", "traceID"}, Doc: "the ID of the distributed trace the entry was logged in."},
+	{Key: "span_id", Type: schema.String, Name: "SpanID", Aliases: []string{"spanId", "spanID"}, Doc: "the ID of the span of the trace the entry was logged in."},
+	{Key: "user_id", Type: schema.String, Name: "UserID", Aliases: []string{"userId", "userID", "uid"}, Doc: "the ID of the user a request was made for."},
+	{Key: "http.method", Type: schema.String, Name: "HTTPMethod", Doc: "the method of an HTTP request."},
+	{Key: "http.path", Type: schema.String, Name: "HTTPPath", Doc: "the path of the URL of an HTTP request, without its query."},
+	{Key: "http.status", Type: schema.Int, Name: "HTTPStatus", Aliases: []string{"status_code", "statusCode"}, Doc: "the status code of an HTTP response."},
+	{Key: "remote_addr", Type: schema.String, Name: "RemoteAddr", Aliases: []string{"remoteAddr", "client_ip"}, Doc: "the network address of the client that sent a request."},
+	{Key: "duration", Type: schema.Duration, Name: "Duration", Aliases: []string{"elapsed", "took", "latency"}, Doc: "the time an operation took."},
+	{Key: "attempt", Type: schema.Int, Name: "Attempt", Aliases: []string{"retry"}, Doc: "the number of the attempt of an operation that is retried, from 1."},
+})
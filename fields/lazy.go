@@ -0,0 +1,23 @@
+package fields
+
+import (
+	"github.com/emiguens/zapfmt/internal/lazy"
+	"go.uber.org/zap"
+)
+
+// Lazy returns a field whose value is computed by fn only once an entry
+// holding it is written, for values too expensive to compute for entries
+// discarded by the logger level, such as a serialized request body:
+//
+//	log.Debug(ctx, "request received", fields.Lazy("body", func() zap.Field {
+//		return zap.String("", dump(r))
+//	}))
+//
+// The field returned by fn is logged under key. Given to With, its value is
+// computed once for the child logger, when it writes its first entry.
+//
+// Lazy fields are only resolved by the loggers of the log package, other
+// zap loggers skip them.
+func Lazy(key string, fn func() zap.Field) zap.Field {
+	return lazy.Field(key, fn)
+}
@@ -0,0 +1,242 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"github.com/emiguens/zapfmt/internal/checked"
+	"github.com/emiguens/zapfmt/internal/lazy"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FlushLevel is the level at which a FlightRecorder writes all the entries
+// it has buffered so far.
+const FlushLevel = zap.ErrorLevel
+
+// FlightRecorder holds in memory the entries logged through a context that
+// would be discarded because of the logger level. Buffered entries are
+// written in the order they were logged when an entry at FlushLevel or above
+// is logged through the same context, or when Flush is called.
+//
+// Entries the logger writes right away aren't held back until the buffered
+// ones logged before them are written, so buffered entries follow them in the
+// output, as when a child logger's level lets some entries through. Their
+// timestamps still tell the order they were logged in.
+//
+// A FlightRecorder keeps a bounded number of entries, once full the oldest
+// entries are discarded first. All methods are safe for concurrent use.
+type FlightRecorder struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	entries []recordedEntry
+	start   int
+	count   int
+	closed  bool
+}
+
+// recordedEntry is a buffered entry along with the core it must be written to.
+type recordedEntry struct {
+	core   zapcore.Core
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+// WithFlightRecorder returns a copy of the parent context in which the logger
+// buffers up to size entries below its level instead of discarding them, and
+// the FlightRecorder holding those entries.
+//
+// The recorder lives as long as the returned context, once it is done the
+// buffered entries are released. A recorder replaces any other recorder
+// previously attached to the parent context logger. Usually a middleware creates the recorder
+// for each request and discards it when the request ends:
+//
+//	ctx, rec := log.WithFlightRecorder(r.Context(), 256)
+//	defer rec.Discard()
+//
+// If the context logger was not created by this package entries are never
+// buffered, but the returned FlightRecorder is still safe to use.
+func WithFlightRecorder(ctx context.Context, size int) (context.Context, *FlightRecorder) {
+	rec := &FlightRecorder{ctx: ctx}
+	if size <= 0 {
+		rec.closed = true
+		return ctx, rec
+	}
+	rec.entries = make([]recordedEntry, size)
+
+	l, ok := getLogger(ctx).(*logger)
+	if !ok {
+		return ctx, rec
+	}
+
+	child := l.Logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return underClock(core, func(core zapcore.Core) zapcore.Core {
+			// A new recorder replaces the one already attached to the logger.
+			if recCore, ok := core.(*recorderCore); ok {
+				core = recCore.Core
+			}
+
+			base := core
+			if lvlCore, ok := core.(*coreWithLevel); ok {
+				base = lvlCore.Core
+			}
+			return &recorderCore{
+				Core: core,
+				base: base,
+				rec:  rec,
+			}
+		})
+	}))
+
+	return context.WithValue(ctx, contextKeyLogger, &logger{Logger: child}), rec
+}
+
+// Flush writes all the buffered entries in the order they were logged and
+// empties the buffer. The recorder keeps buffering new entries afterwards.
+//
+// Entries are checked again by the cores they're written to, so that each
+// core, such as those of a tee, only writes the entries at its level.
+func (r *FlightRecorder) Flush() error {
+	var err error
+	for _, e := range r.drain() {
+		werr := checked.Write(e.core.Check(e.entry, nil), e.entry, lazy.Resolve(e.fields))
+		if err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// Discard drops all the buffered entries and stops buffering new ones.
+func (r *FlightRecorder) Discard() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.entries = nil
+	r.start, r.count = 0, 0
+}
+
+// Len returns the number of entries currently buffered.
+func (r *FlightRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.count
+}
+
+// recording reports whether new entries should be buffered. Once the recorder
+// context is done the buffer is released.
+func (r *FlightRecorder) recording() bool {
+	if r.ctx.Err() != nil {
+		r.Discard()
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !r.closed
+}
+
+// record adds the entry to the buffer, overwriting the oldest one if full.
+func (r *FlightRecorder) record(e recordedEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	size := len(r.entries)
+	if r.count < size {
+		r.entries[(r.start+r.count)%size] = e
+		r.count++
+		return
+	}
+	r.entries[r.start] = e
+	r.start = (r.start + 1) % size
+}
+
+// drain empties the buffer returning its entries in the order they were logged.
+func (r *FlightRecorder) drain() []recordedEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	size := len(r.entries)
+	entries := make([]recordedEntry, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		idx := (r.start + i) % size
+		entries = append(entries, r.entries[idx])
+		r.entries[idx] = recordedEntry{}
+	}
+	r.start, r.count = 0, 0
+	return entries
+}
+
+// recorderCore wraps the logger core and sends to its FlightRecorder all the
+// entries the wrapped core is not going to log.
+//
+// Buffered entries are later written to the base core, which is the wrapped
+// core without any coreWithLevel restriction.
+type recorderCore struct {
+	zapcore.Core
+
+	base zapcore.Core
+	rec  *FlightRecorder
+}
+
+// Enabled returns true if entries at the given level are either logged by the
+// wrapped core or buffered by the recorder.
+func (c *recorderCore) Enabled(level zapcore.Level) bool {
+	return c.Core.Enabled(level) || c.rec.recording()
+}
+
+// Check lets the wrapped core handle the entries it is enabled for, and takes
+// care of the rest by buffering them. Entries at FlushLevel or above are
+// always added so that Write flushes the buffer before they are logged.
+func (c *recorderCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if e.Level >= FlushLevel {
+		ce = ce.AddCore(e, c)
+		return c.Core.Check(e, ce)
+	}
+	if c.Core.Enabled(e.Level) {
+		return c.Core.Check(e, ce)
+	}
+	if !c.rec.recording() {
+		return ce
+	}
+	return ce.AddCore(e, c)
+}
+
+// Write buffers the entry, or flushes the buffer if the entry is at
+// FlushLevel or above.
+func (c *recorderCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	if !c.rec.recording() {
+		return nil
+	}
+	if e.Level >= FlushLevel {
+		return c.rec.Flush()
+	}
+
+	// Fields are owned by the caller, so we keep a copy of them.
+	fs := make([]zapcore.Field, len(fields))
+	copy(fs, fields)
+
+	c.rec.record(recordedEntry{
+		core:   c.base,
+		entry:  e,
+		fields: fs,
+	})
+	return nil
+}
+
+// With adds structured context to both the wrapped and the base core.
+func (c *recorderCore) With(fields []zapcore.Field) zapcore.Core {
+	return &recorderCore{
+		Core: c.Core.With(fields),
+		base: withFields(c.base, fields),
+		rec:  c.rec,
+	}
+}
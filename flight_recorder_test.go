@@ -0,0 +1,168 @@
+package log_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/kami-zh/go-capturer"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	assertMessages := func(t *testing.T, out string, expected ...string) {
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if out == "" {
+			lines = nil
+		}
+
+		if len(lines) != len(expected) {
+			t.Fatalf("expected %d lines, got %d: %q", len(expected), len(lines), out)
+		}
+
+		for i, msg := range expected {
+			if !strings.Contains(lines[i], "[msg:"+msg+"]") {
+				t.Fatalf("expected line %d to contain message %s, got: %s", i, msg, lines[i])
+			}
+		}
+	}
+
+	tt := []struct {
+		Name      string
+		Size      int
+		SetupFunc func(t *testing.T, ctx context.Context, rec *log.FlightRecorder)
+		Expected  []string
+	}{
+		{
+			Name: "Discarded Without Errors",
+			Size: 10,
+			SetupFunc: func(t *testing.T, ctx context.Context, rec *log.FlightRecorder) {
+				log.Debug(ctx, "debug message")
+				log.Info(ctx, "info message")
+
+				if rec.Len() != 2 {
+					t.Fatalf("expected 2 buffered entries, got: %d", rec.Len())
+				}
+				rec.Discard()
+			},
+		},
+		{
+			Name: "Flushed On Error",
+			Size: 10,
+			SetupFunc: func(t *testing.T, ctx context.Context, rec *log.FlightRecorder) {
+				log.Debug(ctx, "debug message")
+				log.Info(ctx, "info message")
+				log.Error(ctx, "error message")
+				log.Debug(ctx, "after error")
+				rec.Discard()
+			},
+			Expected: []string{"debug message", "info message", "error message"},
+		},
+		{
+			Name: "Flushed On Demand",
+			Size: 10,
+			SetupFunc: func(t *testing.T, ctx context.Context, rec *log.FlightRecorder) {
+				ctx = log.With(ctx, zap.String("request_id", "abc"))
+				log.Debug(ctx, "debug message")
+				if err := rec.Flush(); err != nil {
+					t.Fatalf("unexpected error flushing: %v", err)
+				}
+			},
+			Expected: []string{"debug message][request_id:abc"},
+		},
+		{
+			Name: "Keeps Newest Entries",
+			Size: 2,
+			SetupFunc: func(t *testing.T, ctx context.Context, rec *log.FlightRecorder) {
+				log.Debug(ctx, "first")
+				log.Debug(ctx, "second")
+				log.Debug(ctx, "third")
+				log.Error(ctx, "error message")
+			},
+			Expected: []string{"second", "third", "error message"},
+		},
+		{
+			Name: "Child Level Is Written Before Buffered Entries",
+			Size: 10,
+			SetupFunc: func(t *testing.T, ctx context.Context, rec *log.FlightRecorder) {
+				ctx = log.WithLevel(ctx, zap.InfoLevel)
+				log.Debug(ctx, "debug message")
+				log.Info(ctx, "info message")
+
+				if rec.Len() != 1 {
+					t.Fatalf("expected 1 buffered entry, got: %d", rec.Len())
+				}
+				log.Error(ctx, "error message")
+			},
+			Expected: []string{"info message", "debug message", "error message"},
+		},
+		{
+			Name: "Released When Context Is Done",
+			Size: 10,
+			SetupFunc: func(t *testing.T, ctx context.Context, rec *log.FlightRecorder) {
+				ctx, cancel := context.WithCancel(ctx)
+				ctx, rec = log.WithFlightRecorder(ctx, 10)
+
+				log.Debug(ctx, "debug message")
+				cancel()
+				log.Error(ctx, "error message")
+			},
+			Expected: []string{"error message"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			out := capturer.CaptureStderr(func() {
+				lvl := zap.NewAtomicLevelAt(zap.ErrorLevel)
+				ctx := log.Context(context.Background(), log.NewProductionLogger(&lvl))
+				ctx, rec := log.WithFlightRecorder(ctx, tc.Size)
+				tc.SetupFunc(t, ctx, rec)
+			})
+
+			assertMessages(t, out, tc.Expected...)
+		})
+	}
+}
+
+var errDiskFull = errors.New("disk full")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errDiskFull }
+func (failingWriter) Sync() error                 { return nil }
+
+func TestFlightRecorderCores(t *testing.T) {
+	var all, errs zaptest.Buffer
+	enc := encoders.NewKeyValueEncoder(log.NewProductionEncoderConfig())
+	core := zapcore.NewTee(
+		zapcore.NewCore(enc, &all, zap.DebugLevel),
+		zapcore.NewCore(enc, &errs, zap.ErrorLevel),
+	)
+
+	lvl := zap.NewAtomicLevelAt(zap.ErrorLevel)
+	ctx := log.Context(context.Background(), log.New(core, &lvl))
+	ctx, rec := log.WithFlightRecorder(ctx, 10)
+	log.Debug(ctx, "debug message")
+	log.Error(ctx, "error message")
+
+	if lines := all.Lines(); len(lines) != 2 {
+		t.Fatalf("expected 2 lines written to the debug core, got: %q", lines)
+	}
+	if lines := errs.Lines(); len(lines) != 1 || !strings.Contains(lines[0], "[msg:error message]") {
+		t.Fatalf("expected only the error written to the error core, got: %q", lines)
+	}
+
+	core = zapcore.NewCore(enc, failingWriter{}, zap.DebugLevel)
+	ctx = log.Context(context.Background(), log.New(core, &lvl))
+	ctx, rec = log.WithFlightRecorder(ctx, 10)
+	log.Debug(ctx, "debug message")
+	if err := rec.Flush(); !errors.Is(err, errDiskFull) {
+		t.Fatalf("expected disk full error flushing, got: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package grpcadapter implements a grpclog.LoggerV2 writing through the
+// loggers of this package, so the logs of gRPC share the application output:
+//
+//	grpclog.SetLoggerV2(grpcadapter.New(logger.Named("grpc")))
+package grpcadapter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/internal/caller"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/grpclog"
+)
+
+// Logger is a grpclog.LoggerV2 backed by a Logger.
+type Logger struct {
+	logger log.Logger
+}
+
+var _ grpclog.LoggerV2 = &Logger{}
+
+// New returns a grpclog.LoggerV2 writing through the given logger.
+//
+// Entries are attributed to the gRPC code logging them, rather than to the
+// grpclog package.
+func New(l log.Logger) *Logger {
+	return &Logger{logger: l}
+}
+
+// Info logs the arguments formatted as fmt.Sprint at InfoLevel.
+func (l *Logger) Info(args ...interface{}) {
+	l.write(zap.InfoLevel, fmt.Sprint(args...))
+}
+
+// Infoln logs the arguments formatted as fmt.Sprintln at InfoLevel.
+func (l *Logger) Infoln(args ...interface{}) {
+	l.write(zap.InfoLevel, sprintln(args))
+}
+
+// Infof logs the arguments formatted as fmt.Sprintf at InfoLevel.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.write(zap.InfoLevel, fmt.Sprintf(format, args...))
+}
+
+// Warning logs the arguments formatted as fmt.Sprint at WarnLevel.
+func (l *Logger) Warning(args ...interface{}) {
+	l.write(zap.WarnLevel, fmt.Sprint(args...))
+}
+
+// Warningln logs the arguments formatted as fmt.Sprintln at WarnLevel.
+func (l *Logger) Warningln(args ...interface{}) {
+	l.write(zap.WarnLevel, sprintln(args))
+}
+
+// Warningf logs the arguments formatted as fmt.Sprintf at WarnLevel.
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	l.write(zap.WarnLevel, fmt.Sprintf(format, args...))
+}
+
+// Error logs the arguments formatted as fmt.Sprint at ErrorLevel.
+func (l *Logger) Error(args ...interface{}) {
+	l.write(zap.ErrorLevel, fmt.Sprint(args...))
+}
+
+// Errorln logs the arguments formatted as fmt.Sprintln at ErrorLevel.
+func (l *Logger) Errorln(args ...interface{}) {
+	l.write(zap.ErrorLevel, sprintln(args))
+}
+
+// Errorf logs the arguments formatted as fmt.Sprintf at ErrorLevel.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.write(zap.ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs the arguments formatted as fmt.Sprint at FatalLevel and then
+// calls os.Exit(1).
+func (l *Logger) Fatal(args ...interface{}) {
+	l.write(zap.FatalLevel, fmt.Sprint(args...))
+}
+
+// Fatalln logs the arguments formatted as fmt.Sprintln at FatalLevel and
+// then calls os.Exit(1).
+func (l *Logger) Fatalln(args ...interface{}) {
+	l.write(zap.FatalLevel, sprintln(args))
+}
+
+// Fatalf logs the arguments formatted as fmt.Sprintf at FatalLevel and then
+// calls os.Exit(1).
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.write(zap.FatalLevel, fmt.Sprintf(format, args...))
+}
+
+// V reports whether the given verbosity is logged. Verbosity 0 is logged at
+// InfoLevel and any greater verbosity at DebugLevel.
+func (l *Logger) V(level int) bool {
+	lvl := zap.InfoLevel
+	if level > 0 {
+		lvl = zap.DebugLevel
+	}
+
+	core, ok := l.logger.(interface{ Core() zapcore.Core })
+	if !ok {
+		return true
+	}
+	return core.Core().Enabled(lvl)
+}
+
+func (l *Logger) write(lvl zapcore.Level, msg string) {
+	ce := l.logger.Check(lvl, msg)
+	if ce == nil {
+		// Loggers created by this package always exit on FatalLevel, but
+		// other implementations may not.
+		if lvl == zap.FatalLevel {
+			os.Exit(1)
+		}
+		return
+	}
+	if ce.Entry.Caller.Defined {
+		ce.Entry.Caller = caller.Find(0,
+			"google.golang.org/grpc/grpclog.",
+			"google.golang.org/grpc/internal/grpclog.",
+			"github.com/emiguens/zapfmt/grpcadapter.",
+		)
+	}
+	ce.Write()
+}
+
+// sprintln formats the arguments as fmt.Sprintln without the newline.
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
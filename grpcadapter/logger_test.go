@@ -0,0 +1,52 @@
+package grpcadapter_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/emiguens/zapfmt/grpcadapter"
+	"github.com/emiguens/zapfmt/logtest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/grpclog"
+)
+
+func TestLogger(t *testing.T) {
+	logger, logs := logtest.New(zap.InfoLevel)
+	grpclog.SetLoggerV2(grpcadapter.New(logger.Named("grpc")))
+
+	grpclog.Info("my ", "Info message")
+	grpclog.Warningf("my %s message", "Warning")
+	grpclog.Errorln("my", "Error", "message")
+	grpclog.Component("transport").Infof("my %s message", "component")
+
+	if !grpclog.V(0) || grpclog.V(2) {
+		t.Fatalf("expected only verbosity 0 to be enabled at InfoLevel")
+	}
+
+	expected := []struct {
+		Level   zapcore.Level
+		Message string
+	}{
+		{zap.InfoLevel, "my Info message"},
+		{zap.WarnLevel, "my Warning message"},
+		{zap.ErrorLevel, "my Error message"},
+		{zap.InfoLevel, "[transport] my component message"},
+	}
+
+	entries := logs.All()
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got: %d", len(expected), len(entries))
+	}
+	for i, e := range entries {
+		if e.Level != expected[i].Level || e.Message != expected[i].Message {
+			t.Fatalf("expected %s entry %q, got %s entry %q", expected[i].Level, expected[i].Message, e.Level, e.Message)
+		}
+		if e.LoggerName != "grpc" {
+			t.Fatalf("expected logger name grpc, got: %s", e.LoggerName)
+		}
+		if file := filepath.Base(e.Caller.File); file != "logger_test.go" {
+			t.Fatalf("expected caller in logger_test.go, got: %s", e.Caller.File)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Package caller finds the code that made a logging call through an adapter
+// of another logging API, so entries are attributed to it instead of to the
+// adapter or the API internals.
+package caller
+
+import (
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// maxDepth is the number of frames looked at when searching for the caller.
+const maxDepth = 32
+
+// Find returns the first frame whose function doesn't start with any of the
+// given prefixes, skipping skip more frames after it. Prefixes are matched
+// against fully qualified function names, such as "log.(*Logger).Output".
+//
+// The returned caller is undefined if no such frame is found.
+func Find(skip int, prefixes ...string) zapcore.EntryCaller {
+	var pcs [maxDepth]uintptr
+	// Skip runtime.Callers and Find itself.
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		if !hasPrefix(frame.Function, prefixes) {
+			if skip == 0 {
+				return zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+			}
+			skip--
+		}
+		if !more {
+			return zapcore.EntryCaller{}
+		}
+	}
+}
+
+func hasPrefix(function string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(function, p) {
+			return true
+		}
+	}
+	return false
+}
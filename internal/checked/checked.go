@@ -0,0 +1,52 @@
+// Package checked writes entries to the cores held by the CheckedEntry a
+// core returns, for cores that wrap another one and write on its behalf, so
+// that whatever the wrapped core decides in Check, like sampling or which
+// cores of a tee take the entry, still applies.
+package checked
+
+import (
+	"os"
+	"reflect"
+	"unsafe"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// coresField locates the cores of a CheckedEntry, which zapcore doesn't
+// expose, and which CheckedEntry.Write only reports the errors of as text.
+var coresField, coresFound = reflect.TypeOf(zapcore.CheckedEntry{}).FieldByName("cores")
+
+// direct reports whether the cores of a CheckedEntry can be written directly.
+var direct = coresFound && coresField.Type == reflect.TypeOf([]zapcore.Core(nil))
+
+// stderr is where errors are reported when the cores can't be written
+// directly, like zap does by default.
+var stderr = zapcore.Lock(os.Stderr)
+
+// Write writes the fields to the cores held by ce, if not nil, as the given
+// entry, which carries the caller and stacktrace added once ce was checked.
+// It returns the errors of the cores as they returned them.
+func Write(ce *zapcore.CheckedEntry, ent zapcore.Entry, fields []zapcore.Field) error {
+	if ce == nil {
+		return nil
+	}
+
+	if !direct {
+		ce.Entry = ent
+		ce.ErrorOutput = stderr
+		ce.Write(fields...)
+		return nil
+	}
+
+	var err error
+	for _, core := range cores(ce) {
+		err = multierr.Append(err, core.Write(ent, fields))
+	}
+	return err
+}
+
+// cores returns the cores held by ce.
+func cores(ce *zapcore.CheckedEntry) []zapcore.Core {
+	return *(*[]zapcore.Core)(unsafe.Pointer(uintptr(unsafe.Pointer(ce)) + coresField.Offset))
+}
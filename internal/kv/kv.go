@@ -0,0 +1,44 @@
+// Package kv converts the loosely typed key value pairs accepted by sugared
+// and adapted logging APIs into zap fields.
+package kv
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// BadKey is the key of the field holding a key given without a value.
+const BadKey = "!BADKEY"
+
+// Fields converts key value pairs to fields. zap fields may be given in place
+// of a pair. Keys that are not strings are formatted with fmt, and a key
+// without a value is written as the value of a BadKey field.
+func Fields(keysAndValues []interface{}) []zapcore.Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+
+	fields := make([]zapcore.Field, 0, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); {
+		if f, ok := keysAndValues[i].(zapcore.Field); ok {
+			fields = append(fields, f)
+			i++
+			continue
+		}
+
+		if i == len(keysAndValues)-1 {
+			fields = append(fields, zap.Any(BadKey, keysAndValues[i]))
+			break
+		}
+
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+		i += 2
+	}
+	return fields
+}
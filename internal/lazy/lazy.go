@@ -0,0 +1,71 @@
+// Package lazy holds fields whose values are computed only once an entry
+// holding them is written, so that expensive values cost nothing when their
+// level is disabled.
+package lazy
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// value is the computation of a lazy field, done at most once.
+type value struct {
+	once  sync.Once
+	fn    func() zapcore.Field
+	field zapcore.Field
+}
+
+// Field returns a field whose value is the one returned by fn, logged under
+// key, computed the first time the field is resolved. Until then it's a
+// no-op field, so encoders not resolving it skip it.
+func Field(key string, fn func() zapcore.Field) zapcore.Field {
+	return zapcore.Field{Key: key, Type: zapcore.SkipType, Interface: &value{fn: fn}}
+}
+
+// Contains reports whether any of the given fields is lazy.
+func Contains(fields []zapcore.Field) bool {
+	for _, f := range fields {
+		if IsLazy(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the given fields with the lazy ones replaced by their
+// values. The fields are returned as given if none is lazy, or copied
+// otherwise, as they're owned by the caller.
+func Resolve(fields []zapcore.Field) []zapcore.Field {
+	if !Contains(fields) {
+		return fields
+	}
+
+	resolved := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		if IsLazy(f) {
+			f = f.Interface.(*value).resolve(f.Key)
+		}
+		resolved[i] = f
+	}
+	return resolved
+}
+
+// IsLazy reports whether f is a lazy field, whose value isn't known yet.
+func IsLazy(f zapcore.Field) bool {
+	_, ok := f.Interface.(*value)
+	return ok && f.Type == zapcore.SkipType
+}
+
+func (v *value) resolve(key string) zapcore.Field {
+	v.once.Do(func() {
+		f := v.fn()
+		if IsLazy(f) {
+			// A lazy field computing another one.
+			f = f.Interface.(*value).resolve(key)
+		}
+		f.Key = key
+		v.field, v.fn = f, nil
+	})
+	return v.field
+}
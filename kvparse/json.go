@@ -0,0 +1,126 @@
+package kvparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// ParseJSON parses a line holding a JSON object, such as the ones written by
+// zap's JSON encoder. The order of the keys is kept.
+func ParseJSON(line []byte) (Record, error) {
+	v, ok := parseJSONValue(trimLineEnding(line))
+	if !ok || v.Kind != ObjectKind {
+		return Record{}, ErrMalformed
+	}
+	return Record{Fields: v.Fields}, nil
+}
+
+// parseJSONValue parses a JSON document holding a single value.
+func parseJSONValue(data []byte) (Value, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := decodeJSON(dec)
+	if err != nil {
+		return Value{}, false
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return Value{}, false
+	}
+	return v, true
+}
+
+// decodeJSON decodes the next value of dec, keeping the order of object keys.
+func decodeJSON(dec *json.Decoder) (Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			v := Value{Kind: ArrayKind, Elems: []Value{}}
+			for dec.More() {
+				e, err := decodeJSON(dec)
+				if err != nil {
+					return Value{}, err
+				}
+				v.Elems = append(v.Elems, e)
+			}
+			_, err := dec.Token()
+			return v, err
+		}
+
+		v := Value{Kind: ObjectKind, Fields: []Field{}}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return Value{}, err
+			}
+			e, err := decodeJSON(dec)
+			if err != nil {
+				return Value{}, err
+			}
+			v.Fields = append(v.Fields, Field{Key: key.(string), Value: e})
+		}
+		_, err := dec.Token()
+		return v, err
+	case string:
+		return Value{Str: t}, nil
+	case json.Number:
+		return Value{Kind: NumberKind, Str: string(t)}, nil
+	case bool:
+		if t {
+			return Value{Kind: BoolKind, Str: "true"}, nil
+		}
+		return Value{Kind: BoolKind, Str: "false"}, nil
+	default:
+		return Value{Kind: NullKind}, nil
+	}
+}
+
+// AppendJSON appends the record to dst as a JSON object, without a line
+// ending.
+func AppendJSON(dst []byte, r Record) []byte {
+	return appendJSON(dst, Value{Kind: ObjectKind, Fields: r.Fields})
+}
+
+func appendJSON(dst []byte, v Value) []byte {
+	switch v.Kind {
+	case ArrayKind:
+		dst = append(dst, '[')
+		for i, e := range v.Elems {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSON(dst, e)
+		}
+		return append(dst, ']')
+	case ObjectKind:
+		dst = append(dst, '{')
+		for i, f := range v.Fields {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendQuoted(dst, f.Key)
+			dst = append(dst, ':')
+			dst = appendJSON(dst, f.Value)
+		}
+		return append(dst, '}')
+	case NullKind:
+		return append(dst, "null"...)
+	case StringKind:
+		return appendQuoted(dst, v.Str)
+	default:
+		return append(dst, v.Str...)
+	}
+}
+
+// appendQuoted appends s as a JSON string.
+func appendQuoted(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	dst = appendEscaped(dst, s)
+	return append(dst, '"')
+}
@@ -0,0 +1,64 @@
+package kvparse_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+func TestParseJSON(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Line     string
+		Expected string
+	}{
+		{
+			Name:     "Keeps Key Order And Types",
+			Line:     `{"ts":"2019-04-08T20:21:32.375067Z","msg":"started","port":8080,"tls":false,"peer":null}` + "\n",
+			Expected: "[ts:2019-04-08T20:21:32.375067Z][msg:started][port:8080][tls:false][peer:null]",
+		},
+		{
+			Name:     "Nested Values",
+			Line:     `{"msg":"m","user":{"name":"jane","roles":["admin","dev"]},"empty":[]}`,
+			Expected: "[msg:m][user:{name:jane][roles:[admin][dev]}][empty:[]]",
+		},
+		{
+			Name:     "Escapes",
+			Line:     `{"msg":"a \"quoted\"\nline \u00e9"}`,
+			Expected: `[msg:a \"quoted\"\nline é]`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			rec, err := kvparse.ParseJSON([]byte(tc.Line))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := string(kvparse.AppendKeyValue(nil, rec)); got != tc.Expected {
+				t.Fatalf("expected %s, got %s", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestParseJSONMalformed(t *testing.T) {
+	for _, line := range []string{"", "[1,2]", `{"msg":`, `{"msg":"m"} trailing`} {
+		if _, err := kvparse.ParseJSON([]byte(line)); !errors.Is(err, kvparse.ErrMalformed) {
+			t.Errorf("expected %q to be malformed, got %v", line, err)
+		}
+	}
+}
+
+func TestAppendJSON(t *testing.T) {
+	rec, err := kvparse.Parse([]byte("[msg:tab\there][n:1][ns:{a:[x][y]}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `{"msg":"tab\there","n":1,"ns":{"a":["x","y"]}}`
+	if got := string(kvparse.AppendJSON(nil, rec)); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,376 @@
+// Package kvparse parses the lines written by the key value encoder back into
+// records, so that tools can filter, aggregate and convert them. JSON and
+// logfmt lines are parsed too, and records can be written in any of the three
+// formats.
+//
+// The key value format doesn't quote strings nor escape brackets, so parsing
+// is a best effort: values are split on the "][" separators outside of nested
+// arrays and objects, and a separator inside a string value is told apart
+// when the text after it has no key.
+package kvparse
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrMalformed is returned when a line can't be parsed.
+var ErrMalformed = errors.New("kvparse: malformed line")
+
+// Kind is the type of a value.
+type Kind uint8
+
+const (
+	// StringKind is the kind of text values.
+	StringKind Kind = iota
+	// NumberKind is the kind of numeric values, kept as written in Value.Str.
+	NumberKind
+	// BoolKind is the kind of true and false.
+	BoolKind
+	// NullKind is the kind of JSON nulls.
+	NullKind
+	// ArrayKind is the kind of arrays, whose elements are in Value.Elems.
+	ArrayKind
+	// ObjectKind is the kind of objects and namespaces, whose fields are in
+	// Value.Fields.
+	ObjectKind
+)
+
+// Value is a parsed value.
+type Value struct {
+	Kind Kind
+
+	// Str is the unescaped text of strings, numbers and bools.
+	Str string
+
+	Elems  []Value
+	Fields []Field
+}
+
+// String returns the text of a string value, or the key value encoding of
+// any other value.
+func (v Value) String() string {
+	if v.Kind == StringKind {
+		return v.Str
+	}
+	return string(appendKeyValue(nil, v))
+}
+
+// Field is a key and its value.
+type Field struct {
+	Key   string
+	Value Value
+}
+
+// Record is a parsed line. Fields are kept in the order they were written,
+// duplicated keys included.
+type Record struct {
+	Fields []Field
+}
+
+// headerKeys are the keys the production logger writes before the fields.
+// Their values are always parsed as strings, so that a message reading "404"
+// isn't taken for a number.
+var headerKeys = map[string]bool{
+	"ts":         true,
+	"level":      true,
+	"logger":     true,
+	"caller":     true,
+	"msg":        true,
+	"stacktrace": true,
+}
+
+// Parse parses a line written by the key value encoder, such as
+//
+//	[ts:2019-04-08T20:21:32.375067Z][level:info][msg:started][port:8080]
+//
+// Numbers and bools are told apart from strings by their text, while nested
+// arrays, objects and the JSON of reflected values are parsed into their
+// elements and fields.
+func Parse(line []byte) (Record, error) {
+	line = trimLineEnding(line)
+	if len(line) < 2 || line[0] != '[' || line[len(line)-1] != ']' {
+		return Record{}, ErrMalformed
+	}
+
+	pairs, ok := splitPairs(split(string(line[1:len(line)-1]), true))
+	if !ok {
+		return Record{}, ErrMalformed
+	}
+
+	fields := make([]Field, len(pairs))
+	for i, p := range pairs {
+		key := unescape(p[0])
+		if headerKeys[key] {
+			fields[i] = Field{Key: key, Value: Value{Str: unescape(p[1])}}
+			continue
+		}
+		fields[i] = Field{Key: key, Value: parseValue(p[1])}
+	}
+	return Record{Fields: fields}, nil
+}
+
+// split splits the text inside the outer brackets of a line, array or object
+// on its "][" separators, joining back the parts of the nested arrays and
+// objects that had separators in them. With keyed set, parts are key value
+// pairs whose values are checked for nesting.
+func split(s string, keyed bool) []string {
+	parts := strings.Split(s, "][")
+	joined := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		v := part
+		if keyed {
+			v = part[strings.IndexByte(part, ':')+1:]
+		}
+		if v == "" || v[0] != '[' && v[0] != '{' {
+			joined = append(joined, part)
+			continue
+		}
+
+		var n nesting
+		n.scan(v)
+		for j := i + 1; j < len(parts) && n.open(); j++ {
+			n.scan("][" + parts[j])
+			if !n.open() && n.depth == 0 {
+				part = strings.Join(parts[i:j+1], "][")
+				i = j
+			}
+		}
+		joined = append(joined, part)
+	}
+	return joined
+}
+
+// nesting tracks the brackets and JSON strings opened by the text scanned.
+type nesting struct {
+	depth  int
+	quoted bool
+	broken bool
+}
+
+// open reports whether the text scanned so far left nested values open.
+func (n *nesting) open() bool {
+	return !n.broken && (n.depth > 0 || n.quoted)
+}
+
+func (n *nesting) scan(s string) {
+	for i := 0; i < len(s) && !n.broken; i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case n.quoted:
+			n.quoted = c != '"'
+		case c == '"':
+			n.quoted = true
+		case c == '[' || c == '{':
+			n.depth++
+		case c == ']' || c == '}':
+			n.depth--
+			n.broken = n.depth < 0
+		}
+	}
+}
+
+// splitPairs splits the parts of a line or object into keys and values. A part
+// without a key belongs to the value before it, which had a separator in it.
+func splitPairs(parts []string) ([][2]string, bool) {
+	pairs := make([][2]string, 0, len(parts))
+	for _, part := range parts {
+		i := strings.IndexByte(part, ':')
+		if i <= 0 || strings.IndexByte(part[:i], ' ') >= 0 {
+			if len(pairs) == 0 {
+				return nil, false
+			}
+			pairs[len(pairs)-1][1] += "][" + part
+			continue
+		}
+		pairs = append(pairs, [2]string{part[:i], part[i+1:]})
+	}
+	return pairs, true
+}
+
+// parseValue parses the raw text of a value.
+func parseValue(raw string) Value {
+	n := len(raw)
+	if n >= 2 && (raw[0] == '{' && raw[n-1] == '}' || raw[0] == '[' && raw[n-1] == ']') {
+		if v, ok := parseJSONValue([]byte(raw)); ok {
+			return v
+		}
+		if raw[0] == '[' {
+			parts := split(raw[1:n-1], false)
+			elems := make([]Value, len(parts))
+			for i, p := range parts {
+				elems[i] = parseValue(p)
+			}
+			return Value{Kind: ArrayKind, Elems: elems}
+		}
+		if pairs, ok := splitPairs(split(raw[1:n-1], true)); ok {
+			fields := make([]Field, len(pairs))
+			for i, p := range pairs {
+				fields[i] = Field{Key: unescape(p[0]), Value: parseValue(p[1])}
+			}
+			return Value{Kind: ObjectKind, Fields: fields}
+		}
+	}
+	return scalar(raw)
+}
+
+// scalar parses the text of a string, number or bool.
+func scalar(raw string) Value {
+	switch {
+	case raw == "true" || raw == "false":
+		return Value{Kind: BoolKind, Str: raw}
+	case isNumber(raw):
+		return Value{Kind: NumberKind, Str: raw}
+	}
+	return Value{Str: unescape(raw)}
+}
+
+// isNumber reports whether s is a number as JSON writes them.
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s[0] == '-' {
+		s = s[1:]
+	}
+	digits := func() int {
+		n := 0
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		s = s[n:]
+		return n
+	}
+	if s == "" || s[0] == '0' && len(s) > 1 && s[1] >= '0' && s[1] <= '9' {
+		return false
+	}
+	if digits() == 0 {
+		return false
+	}
+	if s != "" && s[0] == '.' {
+		s = s[1:]
+		if digits() == 0 {
+			return false
+		}
+	}
+	if s != "" && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s != "" && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return s == ""
+}
+
+// unescape reverts the JSON escaping of the key value encoder.
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var u string
+	if err := json.Unmarshal([]byte(`"`+s+`"`), &u); err != nil {
+		return s
+	}
+	return u
+}
+
+func trimLineEnding(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line
+}
+
+// AppendKeyValue appends the record to dst in the key value format, without
+// a line ending.
+func AppendKeyValue(dst []byte, r Record) []byte {
+	if len(r.Fields) == 0 {
+		return append(dst, "[]"...)
+	}
+	for _, f := range r.Fields {
+		dst = append(dst, '[')
+		dst = appendEscaped(dst, f.Key)
+		dst = append(dst, ':')
+		dst = appendKeyValue(dst, f.Value)
+		dst = append(dst, ']')
+	}
+	return dst
+}
+
+func appendKeyValue(dst []byte, v Value) []byte {
+	switch v.Kind {
+	case ArrayKind:
+		dst = append(dst, '[')
+		for i, e := range v.Elems {
+			if i > 0 {
+				dst = append(dst, "]["...)
+			}
+			dst = appendKeyValue(dst, e)
+		}
+		return append(dst, ']')
+	case ObjectKind:
+		dst = append(dst, '{')
+		for i, f := range v.Fields {
+			if i > 0 {
+				dst = append(dst, "]["...)
+			}
+			dst = appendEscaped(dst, f.Key)
+			dst = append(dst, ':')
+			dst = appendKeyValue(dst, f.Value)
+		}
+		return append(dst, '}')
+	case NullKind:
+		return append(dst, "null"...)
+	case StringKind:
+		return appendEscaped(dst, v.Str)
+	default:
+		return append(dst, v.Str...)
+	}
+}
+
+const hex = "0123456789abcdef"
+
+// appendEscaped appends s JSON-escaped, without quotes, as the key value
+// encoder writes strings.
+func appendEscaped(dst []byte, s string) []byte {
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			switch {
+			case b >= 0x20 && b != '\\' && b != '"':
+				dst = append(dst, b)
+			case b == '\\' || b == '"':
+				dst = append(dst, '\\', b)
+			case b == '\n':
+				dst = append(dst, '\\', 'n')
+			case b == '\r':
+				dst = append(dst, '\\', 'r')
+			case b == '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, `\ufffd`...)
+		} else {
+			dst = append(dst, s[i:i+size]...)
+		}
+		i += size
+	}
+	return dst
+}
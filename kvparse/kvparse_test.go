@@ -0,0 +1,137 @@
+package kvparse_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/kvparse"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Line     string
+		Expected string
+	}{
+		{
+			Name:     "Header And Fields",
+			Line:     "[ts:2019-04-08T20:21:32.375067Z][level:info][logger:http][caller:zapfmt/main.go:12][msg:started][port:8080]\n",
+			Expected: `{"ts":"2019-04-08T20:21:32.375067Z","level":"info","logger":"http","caller":"zapfmt/main.go:12","msg":"started","port":8080}`,
+		},
+		{
+			Name:     "Scalars",
+			Line:     "[msg:404][n:-1.5e3][ok:true][s:0123][d:1s]",
+			Expected: `{"msg":"404","n":-1.5e3,"ok":true,"s":"0123","d":"1s"}`,
+		},
+		{
+			Name:     "Escaped Strings",
+			Line:     `[msg:a \"quoted\"\nline][path:C:\\tmp]`,
+			Expected: `{"msg":"a \"quoted\"\nline","path":"C:\\tmp"}`,
+		},
+		{
+			Name:     "Arrays",
+			Line:     "[msg:m][strings:[a][b][c]][nested:[[1][2]][[3]]][json:[\"x\",\"y\"]]",
+			Expected: `{"msg":"m","strings":["a","b","c"],"nested":[[1,2],[3]],"json":["x","y"]}`,
+		},
+		{
+			Name:     "Objects And Namespaces",
+			Line:     `[msg:m][user:{name:jane][roles:[admin][dev]}][http:{status:200][req:{method:GET}}][reflect:{"object":["a","b"]}]`,
+			Expected: `{"msg":"m","user":{"name":"jane","roles":["admin","dev"]},"http":{"status":200,"req":{"method":"GET"}},"reflect":{"object":["a","b"]}}`,
+		},
+		{
+			Name:     "Separator In String",
+			Line:     "[msg:a][b][c][retry in: 5s][k:v]",
+			Expected: `{"msg":"a][b][c][retry in: 5s","k":"v"}`,
+		},
+		{
+			Name:     "Unbalanced Brackets In String",
+			Line:     "[msg:got [x][k:v]",
+			Expected: `{"msg":"got [x","k":"v"}`,
+		},
+		{
+			Name:     "CRLF",
+			Line:     "[msg:m]\r\n",
+			Expected: `{"msg":"m"}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			rec, err := kvparse.Parse([]byte(tc.Line))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := string(kvparse.AppendJSON(nil, rec)); got != tc.Expected {
+				t.Fatalf("expected %s, got %s", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, line := range []string{"", "plain text", "[no key]", "[:v]", "[msg:m"} {
+		if _, err := kvparse.Parse([]byte(line)); !errors.Is(err, kvparse.ErrMalformed) {
+			t.Errorf("expected %q to be malformed, got %v", line, err)
+		}
+	}
+}
+
+type user struct {
+	name  string
+	roles []string
+}
+
+func (u user) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.name)
+	return enc.AddArray("roles", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, r := range u.roles {
+			arr.AppendString(r)
+		}
+		return nil
+	}))
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	enc := encoders.NewKeyValueEncoder(zapcore.EncoderConfig{
+		TimeKey:     "ts",
+		LevelKey:    "level",
+		MessageKey:  "msg",
+		EncodeLevel: zapcore.LowercaseLevelEncoder,
+		EncodeTime:  zapcore.ISO8601TimeEncoder,
+	})
+	ent := zapcore.Entry{
+		Level:   zap.WarnLevel,
+		Time:    time.Date(2019, time.April, 8, 20, 21, 32, 0, time.UTC),
+		Message: "tricky [message]: \"quoted\"\n",
+	}
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.String("s", "a]b[c"),
+		zap.Int("n", 42),
+		zap.Float64("f", 0.25),
+		zap.Bool("b", false),
+		zap.Object("user", user{name: "jane", roles: []string{"admin", "dev"}}),
+		zap.Namespace("ns"),
+		zap.String("k", "v"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := kvparse.Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error parsing %q: %v", buf.String(), err)
+	}
+
+	const expected = `{"ts":"2019-04-08T20:21:32.000Z","level":"warn","msg":"tricky [message]: \"quoted\"\n","s":"a]b[c","n":42,"f":0.25,"b":false,"user":{"name":"jane","roles":["admin","dev"]},"ns":{"k":"v"}}`
+	if got := string(kvparse.AppendJSON(nil, rec)); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+
+	if got := string(kvparse.AppendKeyValue(nil, rec)) + "\n"; got != buf.String() {
+		t.Fatalf("expected %q, got %q", buf.String(), got)
+	}
+}
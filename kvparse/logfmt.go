@@ -0,0 +1,144 @@
+package kvparse
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseLogfmt parses a logfmt line, such as
+//
+//	ts=2019-04-08T20:21:32.375067Z level=info msg="server started" port=8080
+//
+// Unquoted values are told apart as numbers and bools by their text, and a
+// key without a value is taken as true. Dotted keys are kept as they are.
+func ParseLogfmt(line []byte) (Record, error) {
+	s := string(trimLineEnding(line))
+
+	var fields []Field
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+
+		i := strings.IndexAny(s, "= \t")
+		if i == 0 || i > 0 && strings.IndexByte(s[:i], '"') >= 0 {
+			return Record{}, ErrMalformed
+		}
+		if i < 0 || s[i] != '=' {
+			if i < 0 {
+				i = len(s)
+			}
+			fields = append(fields, Field{Key: s[:i], Value: Value{Kind: BoolKind, Str: "true"}})
+			s = s[i:]
+			continue
+		}
+
+		key := s[:i]
+		s = s[i+1:]
+
+		if strings.HasPrefix(s, `"`) {
+			n, err := quotedPrefix(s)
+			if err != nil {
+				return Record{}, ErrMalformed
+			}
+			str, err := strconv.Unquote(s[:n])
+			if err != nil {
+				return Record{}, ErrMalformed
+			}
+			fields = append(fields, Field{Key: key, Value: Value{Str: str}})
+			s = s[n:]
+			continue
+		}
+
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		v := Value{Str: s[:end]}
+		if !headerKeys[key] {
+			v = scalar(s[:end])
+			v.Str = s[:end]
+		}
+		fields = append(fields, Field{Key: key, Value: v})
+		s = s[end:]
+	}
+
+	if len(fields) == 0 {
+		return Record{}, ErrMalformed
+	}
+	return Record{Fields: fields}, nil
+}
+
+// quotedPrefix returns the length of the quoted string s starts with.
+func quotedPrefix(s string) (int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, ErrMalformed
+}
+
+// AppendLogfmt appends the record to dst in the logfmt format, without a line
+// ending. Objects are flattened into dotted keys, and arrays are written as
+// quoted JSON.
+func AppendLogfmt(dst []byte, r Record) []byte {
+	return appendLogfmtFields(dst, len(dst), "", r.Fields)
+}
+
+// appendLogfmtFields appends the fields to dst, which holds the record from
+// start on.
+func appendLogfmtFields(dst []byte, start int, prefix string, fields []Field) []byte {
+	for _, f := range fields {
+		key := prefix + f.Key
+		if f.Value.Kind == ObjectKind && len(f.Value.Fields) > 0 {
+			dst = appendLogfmtFields(dst, start, key+".", f.Value.Fields)
+			continue
+		}
+
+		if len(dst) > start {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, logfmtKey(key)...)
+		dst = append(dst, '=')
+		switch f.Value.Kind {
+		case StringKind:
+			dst = appendLogfmtValue(dst, f.Value.Str)
+		case ArrayKind, ObjectKind:
+			dst = appendLogfmtValue(dst, string(appendJSON(nil, f.Value)))
+		case NullKind:
+			dst = append(dst, "null"...)
+		default:
+			dst = append(dst, f.Value.Str...)
+		}
+	}
+	return dst
+}
+
+// logfmtKey replaces the characters logfmt keys can't hold with underscores.
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// appendLogfmtValue appends s, quoted if it's empty or has spaces, quotes,
+// equal signs or control characters.
+func appendLogfmtValue(dst []byte, s string) []byte {
+	if s == "" || strings.IndexFunc(s, needsQuote) >= 0 {
+		return strconv.AppendQuote(dst, s)
+	}
+	return append(dst, s...)
+}
+
+func needsQuote(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f
+}
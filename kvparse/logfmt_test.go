@@ -0,0 +1,69 @@
+package kvparse_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Line     string
+		Expected string
+	}{
+		{
+			Name:     "Quoted And Bare Values",
+			Line:     `ts=2019-04-08T20:21:32.375067Z level=info msg="server started" port=8080 tls=false` + "\n",
+			Expected: `{"ts":"2019-04-08T20:21:32.375067Z","level":"info","msg":"server started","port":8080,"tls":false}`,
+		},
+		{
+			Name:     "Keys Without Values",
+			Line:     `msg=m  retry  debug`,
+			Expected: `{"msg":"m","retry":true,"debug":true}`,
+		},
+		{
+			Name:     "Escapes In Quotes",
+			Line:     `msg="a \"quoted\"\nline" path=C:\tmp`,
+			Expected: `{"msg":"a \"quoted\"\nline","path":"C:\\tmp"}`,
+		},
+		{
+			Name:     "Header Values Are Strings",
+			Line:     `msg=404 status=404`,
+			Expected: `{"msg":"404","status":404}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			rec, err := kvparse.ParseLogfmt([]byte(tc.Line))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := string(kvparse.AppendJSON(nil, rec)); got != tc.Expected {
+				t.Fatalf("expected %s, got %s", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestParseLogfmtMalformed(t *testing.T) {
+	for _, line := range []string{"", "   ", `msg="unterminated`, `=v`, `a"b=c`} {
+		if _, err := kvparse.ParseLogfmt([]byte(line)); !errors.Is(err, kvparse.ErrMalformed) {
+			t.Errorf("expected %q to be malformed, got %v", line, err)
+		}
+	}
+}
+
+func TestAppendLogfmt(t *testing.T) {
+	rec, err := kvparse.Parse([]byte(`[msg:server started][port:8080][empty:[]][http:{status:200][req:{method:GET}}][tags:[a][b]][path:a=b][q:say \"hi\"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `msg="server started" port=8080 empty=[] http.status=200 http.req.method=GET tags="[\"a\",\"b\"]" path="a=b" q="say \"hi\""`
+	if got := string(kvparse.AppendLogfmt([]byte{}, rec)); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,96 @@
+package kvparse
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Get returns the value of the given key. If the key is duplicated, the last
+// value is returned, as most readers of duplicated keys do.
+//
+// When no field has the key, a dotted key such as http.status looks into the
+// nested objects, which is how namespaces are written.
+func (r Record) Get(key string) (Value, bool) {
+	fields := r.Fields
+	for {
+		if v, ok := get(fields, key); ok {
+			return v, true
+		}
+
+		i := strings.IndexByte(key, '.')
+		if i < 0 {
+			return Value{}, false
+		}
+		v, ok := get(fields, key[:i])
+		if !ok || v.Kind != ObjectKind {
+			return Value{}, false
+		}
+		fields, key = v.Fields, key[i+1:]
+	}
+}
+
+func get(fields []Field, key string) (Value, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == key {
+			return fields[i].Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// Time returns the time of the record, from its ts key. Besides RFC3339
+// timestamps, numbers are read as milliseconds or nanoseconds since the Unix
+// epoch, telling them apart by their magnitude, as written with
+// log.EpochMillisTime and log.EpochNanosTime.
+func (r Record) Time() (time.Time, bool) {
+	v, ok := r.Get("ts")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if n, err := strconv.ParseInt(v.Str, 10, 64); err == nil {
+		if n > 1e15 || n < -1e15 {
+			return time.Unix(0, n).UTC(), true
+		}
+		return time.Unix(0, n*int64(time.Millisecond)).UTC(), true
+	}
+	t, err := time.Parse(time.RFC3339Nano, v.Str)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Level returns the level of the record, from its level key.
+func (r Record) Level() (zapcore.Level, bool) {
+	v, ok := r.Get("level")
+	if !ok {
+		return 0, false
+	}
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(v.Str)); err != nil {
+		return 0, false
+	}
+	return lvl, true
+}
+
+// Float returns the value of a number, or of a string holding a number or a
+// duration such as "1.5s", in which case it's in seconds.
+func (v Value) Float() (float64, bool) {
+	switch v.Kind {
+	case NumberKind:
+		f, err := strconv.ParseFloat(v.Str, 64)
+		return f, err == nil
+	case StringKind:
+		if f, err := strconv.ParseFloat(v.Str, 64); err == nil {
+			return f, true
+		}
+		if d, err := time.ParseDuration(v.Str); err == nil {
+			return d.Seconds(), true
+		}
+	}
+	return 0, false
+}
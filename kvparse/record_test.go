@@ -0,0 +1,68 @@
+package kvparse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emiguens/zapfmt/kvparse"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRecordTime(t *testing.T) {
+	expected := time.Date(2019, time.April, 8, 20, 21, 32, 375000000, time.UTC)
+
+	for _, line := range []string{
+		"[ts:2019-04-08T20:21:32.375000Z][msg:rfc3339]",
+		"[ts:2019-04-08T17:21:32.375000-03:00][msg:rfc3339 with zone]",
+		"[ts:1554754892375][msg:millis]",
+		"[ts:1554754892375000000][msg:nanos]",
+	} {
+		rec, err := kvparse.Parse([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := rec.Time()
+		if !ok || !got.Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", line, expected, got)
+		}
+	}
+
+	rec, _ := kvparse.Parse([]byte("[ts:yesterday][msg:m]"))
+	if _, ok := rec.Time(); ok {
+		t.Error("expected invalid time to be reported")
+	}
+}
+
+func TestRecordGet(t *testing.T) {
+	rec, err := kvparse.Parse([]byte("[level:WARN][msg:m][id:a][id:b][elapsed:1.5s][n:2][a.b:dotted][http:{status:200][req:{method:GET}}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := rec.Get("id"); !ok || v.Str != "b" {
+		t.Errorf("expected last duplicated value, got %v", v)
+	}
+	for _, key := range []string{"missing", "http.missing", "http.status.code", "msg.x"} {
+		if _, ok := rec.Get(key); ok {
+			t.Errorf("expected missing key %s to be reported", key)
+		}
+	}
+	for key, expected := range map[string]string{"a.b": "dotted", "http.status": "200", "http.req.method": "GET"} {
+		if v, ok := rec.Get(key); !ok || v.Str != expected {
+			t.Errorf("%s: expected %s, got %v", key, expected, v)
+		}
+	}
+	if lvl, ok := rec.Level(); !ok || lvl != zapcore.WarnLevel {
+		t.Errorf("expected warn level, got %v", lvl)
+	}
+
+	for key, expected := range map[string]float64{"elapsed": 1.5, "n": 2} {
+		v, _ := rec.Get(key)
+		if f, ok := v.Float(); !ok || f != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, f)
+		}
+	}
+	if v, _ := rec.Get("msg"); func() bool { _, ok := v.Float(); return ok }() {
+		t.Error("expected text not to be a number")
+	}
+}
@@ -0,0 +1,88 @@
+package kvparse
+
+import (
+	"bufio"
+	"io"
+)
+
+// DefaultMaxLineSize is the longest line a Scanner reads whole, unless
+// Scanner.MaxLineSize says otherwise.
+const DefaultMaxLineSize = 1 << 20
+
+// Scanner reads lines one at a time, so that files of any size are read in
+// constant memory. Lines longer than MaxLineSize are cut, and the rest of
+// them is skipped.
+type Scanner struct {
+	// MaxLineSize is the longest line read whole, DefaultMaxLineSize if zero.
+	MaxLineSize int
+
+	r         *bufio.Reader
+	line      []byte
+	truncated bool
+	err       error
+}
+
+// NewScanner returns a scanner reading lines from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReader(r)}
+}
+
+// Scan reads the next line, returning false at the end of the input or on
+// errors.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	max := s.MaxLineSize
+	if max <= 0 {
+		max = DefaultMaxLineSize
+	}
+
+	s.line = s.line[:0]
+	s.truncated = false
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		if room := max - len(s.line); len(chunk) > room {
+			chunk = chunk[:room]
+			s.truncated = true
+		}
+		s.line = append(s.line, chunk...)
+
+		switch err {
+		case nil:
+			s.line = trimLineEnding(s.line)
+			return true
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			if len(s.line) > 0 || s.truncated {
+				s.line = trimLineEnding(s.line)
+				s.err = io.EOF
+				return true
+			}
+		}
+		s.err = err
+		return false
+	}
+}
+
+// Line returns the last line read, without its line ending. It's only valid
+// until the next call to Scan.
+func (s *Scanner) Line() []byte {
+	return s.line
+}
+
+// Truncated reports whether the last line read was longer than MaxLineSize.
+func (s *Scanner) Truncated() bool {
+	return s.truncated
+}
+
+// Err returns the error that stopped the scanner, if it wasn't the end of
+// the input.
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
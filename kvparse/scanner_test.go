@@ -0,0 +1,64 @@
+package kvparse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emiguens/zapfmt/kvparse"
+)
+
+func TestScanner(t *testing.T) {
+	tt := []struct {
+		Name        string
+		Input       string
+		MaxLineSize int
+		Lines       []string
+		Truncated   []bool
+	}{
+		{
+			Name:      "Line Endings",
+			Input:     "[msg:a]\n[msg:b]\r\n\n[msg:c]",
+			Lines:     []string{"[msg:a]", "[msg:b]", "", "[msg:c]"},
+			Truncated: []bool{false, false, false, false},
+		},
+		{
+			Name:      "Long Lines",
+			Input:     "[msg:" + strings.Repeat("x", 10000) + "]\n[msg:b]\n",
+			Lines:     []string{"[msg:" + strings.Repeat("x", 10000) + "]", "[msg:b]"},
+			Truncated: []bool{false, false},
+		},
+		{
+			Name:        "Lines Over The Limit",
+			Input:       "[msg:" + strings.Repeat("x", 10000) + "]\n[msg:b]\n[msg:" + strings.Repeat("y", 10000),
+			MaxLineSize: 8,
+			Lines:       []string{"[msg:xxx", "[msg:b]", "[msg:yyy"},
+			Truncated:   []bool{true, false, true},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			sc := kvparse.NewScanner(strings.NewReader(tc.Input))
+			sc.MaxLineSize = tc.MaxLineSize
+
+			var lines []string
+			var truncated []bool
+			for sc.Scan() {
+				lines = append(lines, string(sc.Line()))
+				truncated = append(truncated, sc.Truncated())
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Join(lines, "\n") != strings.Join(tc.Lines, "\n") {
+				t.Fatalf("expected lines %q, got %q", tc.Lines, lines)
+			}
+			for i := range truncated {
+				if truncated[i] != tc.Truncated[i] {
+					t.Fatalf("expected truncated %v, got %v", tc.Truncated, truncated)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/emiguens/zapfmt/internal/lazy"
+	"go.uber.org/zap/zapcore"
+)
+
+// lazyFields returns the given fields wrapped as lazy fields, so that they're
+// encoded only once an entry is written.
+func lazyFields(fields []zapcore.Field) []zapcore.Field {
+	wrapped := make([]zapcore.Field, len(fields))
+	for i := range fields {
+		f := fields[i]
+		wrapped[i] = lazy.Field(f.Key, func() zapcore.Field { return f })
+	}
+	return wrapped
+}
+
+// withFields adds structured context to the given core, deferring it until
+// an entry is written if any of the fields is lazy.
+func withFields(core zapcore.Core, fields []zapcore.Field) zapcore.Core {
+	if !lazy.Contains(fields) {
+		return core.With(fields)
+	}
+	return &lazyCore{parent: core, fields: fields}
+}
+
+// lazyCore adds fields to its parent core the first time it checks or writes
+// an entry, so that their lazy values are computed once and only if an entry
+// is written.
+type lazyCore struct {
+	parent zapcore.Core
+	fields []zapcore.Field
+
+	once sync.Once
+	core zapcore.Core
+}
+
+// resolved returns the parent core with the fields added.
+func (c *lazyCore) resolved() zapcore.Core {
+	c.once.Do(func() {
+		parent := c.parent
+		if lzCore, ok := parent.(*lazyCore); ok {
+			parent = lzCore.resolved()
+		}
+		c.core = parent.With(lazy.Resolve(c.fields))
+	})
+	return c.core
+}
+
+// Enabled asks the parent core, whose levels don't depend on the fields.
+func (c *lazyCore) Enabled(level zapcore.Level) bool {
+	return c.parent.Enabled(level)
+}
+
+// With defers the new fields too, as the ones of the core may still have to
+// be added first.
+func (c *lazyCore) With(fields []zapcore.Field) zapcore.Core {
+	return &lazyCore{parent: c, fields: fields}
+}
+
+func (c *lazyCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.resolved().Check(e, ce)
+}
+
+func (c *lazyCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return c.resolved().Write(e, fields)
+}
+
+// Sync syncs the parent core, which writes to the same output.
+func (c *lazyCore) Sync() error {
+	return c.parent.Sync()
+}
@@ -0,0 +1,152 @@
+package log_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/fields"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestLazyFields(t *testing.T) {
+	tt := []struct {
+		Name      string
+		SetupFunc func(ctx context.Context, lazy func(key string) zap.Field)
+		Expected  []string
+		Computed  int
+	}{
+		{
+			Name: "Not Computed When Level Disabled",
+			SetupFunc: func(ctx context.Context, lazy func(string) zap.Field) {
+				log.Debug(ctx, "request received", lazy("body"))
+			},
+		},
+		{
+			Name: "Computed When Written",
+			SetupFunc: func(ctx context.Context, lazy func(string) zap.Field) {
+				log.Info(ctx, "request received", zap.Int("attempt", 1), lazy("body"))
+			},
+			Expected: []string{`[level:info][msg:request received][attempt:1][body:value of body]`},
+			Computed: 1,
+		},
+		{
+			Name: "Computed When Written Through WithLevel",
+			SetupFunc: func(ctx context.Context, lazy func(string) zap.Field) {
+				log.Debug(log.WithLevel(ctx, zap.DebugLevel), "request received", lazy("body"))
+			},
+			Expected: []string{`[level:debug][msg:request received][body:value of body]`},
+			Computed: 1,
+		},
+		{
+			Name: "Computed Once Per Child",
+			SetupFunc: func(ctx context.Context, lazy func(string) zap.Field) {
+				ctx = log.With(ctx, lazy("user"))
+				log.Debug(ctx, "discarded")
+				log.Info(ctx, "first")
+				log.Info(log.With(ctx, zap.Int("attempt", 2)), "second")
+			},
+			Expected: []string{
+				`[level:info][msg:first][user:value of user]`,
+				`[level:info][msg:second][user:value of user][attempt:2]`,
+			},
+			Computed: 1,
+		},
+		{
+			Name: "Not Computed For Child Without Entries",
+			SetupFunc: func(ctx context.Context, lazy func(string) zap.Field) {
+				ctx = log.With(ctx, lazy("user"))
+				log.Debug(ctx, "discarded")
+				log.Debug(log.With(ctx, lazy("session")), "discarded")
+			},
+		},
+		{
+			Name: "WithLazy",
+			SetupFunc: func(ctx context.Context, lazy func(string) zap.Field) {
+				ctx = log.WithLazy(ctx, zap.String("request_id", "abc"), lazy("user"))
+				log.Debug(ctx, "discarded")
+				log.Info(ctx, "first")
+				log.Info(ctx, "second")
+			},
+			Expected: []string{
+				`[level:info][msg:first][request_id:abc][user:value of user]`,
+				`[level:info][msg:second][request_id:abc][user:value of user]`,
+			},
+			Computed: 1,
+		},
+		{
+			Name: "Flushed By Flight Recorder",
+			SetupFunc: func(ctx context.Context, lazy func(string) zap.Field) {
+				ctx, rec := log.WithFlightRecorder(ctx, 10)
+				ctx = log.With(ctx, lazy("user"))
+				log.Debug(ctx, "buffered", lazy("body"))
+				rec.Flush()
+			},
+			Expected: []string{`[level:debug][msg:buffered][user:value of user][body:value of body]`},
+			Computed: 2,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf zaptest.Buffer
+			cfg := log.NewProductionEncoderConfig()
+			cfg.TimeKey = ""
+			cfg.CallerKey = ""
+			core := zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &buf, zap.DebugLevel)
+
+			lvl := zap.NewAtomicLevelAt(zap.InfoLevel)
+			ctx := log.Context(context.Background(), log.New(core, &lvl))
+
+			var computed int
+			tc.SetupFunc(ctx, func(key string) zap.Field {
+				return fields.Lazy(key, func() zap.Field {
+					computed++
+					return zap.String("ignored", "value of "+key)
+				})
+			})
+
+			if got := strings.Join(buf.Lines(), "\n"); got != strings.Join(tc.Expected, "\n") {
+				t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(tc.Expected, "\n"), got)
+			}
+			if computed != tc.Computed {
+				t.Fatalf("expected %d values computed, got %d", tc.Computed, computed)
+			}
+		})
+	}
+}
+
+// plainLogger is a Logger without WithLazy.
+type plainLogger struct {
+	log.Logger
+}
+
+func TestWithLazyFallback(t *testing.T) {
+	var buf zaptest.Buffer
+	cfg := log.NewProductionEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.CallerKey = ""
+	core := zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &buf, zap.DebugLevel)
+
+	lvl := zap.NewAtomicLevelAt(zap.InfoLevel)
+	ctx := log.Context(context.Background(), plainLogger{log.New(core, &lvl)})
+
+	var computed int
+	ctx = log.WithLazy(ctx, fields.Lazy("user", func() zap.Field {
+		computed++
+		return zap.String("user", "abc")
+	}))
+	if computed != 1 {
+		t.Fatalf("expected the value to be computed right away, got %d computations", computed)
+	}
+
+	log.Info(ctx, "request received")
+	expected := "[level:info][msg:request received][user:abc]"
+	if got := strings.Join(buf.Lines(), "\n"); got != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"github.com/emiguens/zapfmt/internal/checked"
+	"github.com/emiguens/zapfmt/internal/lazy"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -37,25 +39,31 @@ func (c *coreWithLevel) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcor
 	// level, if it's not then it returns nil. The `ce` param is by default
 	// nil and if we want to log we must create a new zapcore.CheckedEntry.
 	//
-	// Unfortunately instantiation of this struct happens within zaps ioCore which
-	// is private, so we must delegate to the wrapped c.Core. The wrapped core
-	// will only return non-nil if it is set at a level that accepts the entry
-	// being given, this is the reason of the limitation that coreWithLevel
-	// only works further limiting logging level, and does not allow to
-	// be more flexible than the wrapped core.
-	if !c.lvl.Enabled(e.Level) {
+	// The wrapped core is checked once the entry is written rather than now,
+	// so that lazy fields are resolved for it. It will only accept the entry
+	// if it is set at a level that accepts it, this is the reason of the
+	// limitation that coreWithLevel only works further limiting logging
+	// level, and does not allow to be more flexible than the wrapped core.
+	if !c.Enabled(e.Level) {
 		return ce
 	}
-	return c.Core.Check(e, ce)
+	return ce.AddCore(e, c)
+}
+
+// Write checks the entry with the wrapped core, and writes it with the lazy
+// fields resolved to the cores accepting it. Write errors are returned, so
+// that zap reports them to the logger error output.
+func (c *coreWithLevel) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return checked.Write(c.Core.Check(e, nil), e, lazy.Resolve(fields))
 }
 
 // With adds structured context to the Core. Given how zap works
 // internally (it returns a new private ioCore) new must wrap
-// again the given core within a coreWithLevel.
+// again the given core within a coreWithLevel. Fields with lazy
+// values are added once the child writes an entry.
 func (c *coreWithLevel) With(fields []zapcore.Field) zapcore.Core {
-	core := c.Core.With(fields)
 	return &coreWithLevel{
-		Core: core,
+		Core: withFields(c.Core, fields),
 		lvl:  c.lvl,
 	}
 }
@@ -65,18 +73,39 @@ func (c *coreWithLevel) With(fields []zapcore.Field) zapcore.Core {
 // with the new given level.
 func wrapCoreWithLevel(l *zap.AtomicLevel) zap.Option {
 	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-		newCore := &coreWithLevel{
-			Core: core,
-			lvl:  l,
-		}
+		return withLevel(core, l)
+	})
+}
+
+// withLevel wraps the given core within a coreWithLevel.
+func withLevel(core zapcore.Core, l *zap.AtomicLevel) zapcore.Core {
+	if _, ok := core.(*clockCore); ok {
+		return underClock(core, func(core zapcore.Core) zapcore.Core {
+			return withLevel(core, l)
+		})
+	}
 
-		// If core is a coreWithLevel we want to wrap the underlying core.
-		// The underlying core should be configured at Debug level.
-		lvlCore, ok := core.(*coreWithLevel)
-		if ok {
-			newCore.Core = lvlCore.Core
+	// A recorderCore must remain on top, so that it only buffers
+	// what the new level discards.
+	if recCore, ok := core.(*recorderCore); ok {
+		return &recorderCore{
+			Core: withLevel(recCore.Core, l),
+			base: recCore.base,
+			rec:  recCore.rec,
 		}
+	}
 
-		return newCore
-	})
+	newCore := &coreWithLevel{
+		Core: core,
+		lvl:  l,
+	}
+
+	// If core is a coreWithLevel we want to wrap the underlying core.
+	// The underlying core should be configured at Debug level.
+	lvlCore, ok := core.(*coreWithLevel)
+	if ok {
+		newCore.Core = lvlCore.Core
+	}
+
+	return newCore
 }
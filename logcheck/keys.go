@@ -0,0 +1,221 @@
+//go:build go1.22
+// +build go1.22
+
+package logcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// snakeCase matches the keys following the naming convention.
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// field is a field given to a logging function.
+type field struct {
+	// keyExpr is the expression giving the key, nil if it's not constant,
+	// and value the one given to zap.Any or zap.Reflect, or in a pair.
+	key     string
+	keyExpr ast.Expr
+	value   ast.Expr
+
+	// namespace is whether the field is zap.Namespace, after which keys are
+	// in a new object.
+	namespace bool
+}
+
+// fields checks the fields of a call, given from args[start] on, either as
+// zap fields or as key value pairs.
+func (c *checker) fields(args []ast.Expr, start int, pairs bool) {
+	seen := make(map[string]token.Pos)
+	for i := start; i < len(args); i++ {
+		from := i
+		f, ok := c.field(args[i])
+		if !ok && pairs && !isNamed(c.pass.TypesInfo.TypeOf(args[i]), zapcorePath, "Field") {
+			// A key value pair, whose value is logged with zap.Any.
+			if i+1 == len(args) {
+				return
+			}
+			f.key, ok = stringConstant(c.pass.TypesInfo, args[i])
+			if !ok {
+				// Pairs can't be told apart from fields anymore.
+				return
+			}
+			f.keyExpr, f.value = args[i], args[i+1]
+			i++
+		}
+
+		if f.value != nil {
+			c.value(f.value)
+		}
+		if f.namespace {
+			seen = make(map[string]token.Pos)
+		}
+		if f.keyExpr == nil {
+			continue
+		}
+		if _, ok := f.keyExpr.(*ast.CallExpr); !ok {
+			c.key(f.key, f.keyExpr)
+		}
+		if first, ok := seen[f.key]; ok {
+			if i+1 < len(args) {
+				c.duplicate(f.key, f.keyExpr, first, args[from].Pos(), args[i+1].Pos())
+			} else {
+				c.duplicate(f.key, f.keyExpr, first, args[from-1].End(), args[i].End())
+			}
+			continue
+		}
+		seen[f.key] = f.keyExpr.Pos()
+	}
+}
+
+// field returns the field made by a call to a zap field constructor, and
+// whether e is one.
+func (c *checker) field(e ast.Expr) (field, bool) {
+	call, ok := ast.Unparen(e).(*ast.CallExpr)
+	if !ok {
+		return field{}, false
+	}
+	fn, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+	if !ok || pkgPath(fn) != zapPath {
+		return field{}, false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Results().Len() != 1 || !isNamed(sig.Results().At(0).Type(), zapcorePath, "Field") {
+		return field{}, false
+	}
+
+	var f field
+	switch fn.Name() {
+	case "Error":
+		// The key is implied.
+		return field{key: "error", keyExpr: call}, true
+	case "Namespace":
+		f.namespace = true
+	case "Any", "Reflect":
+		if len(call.Args) == 2 {
+			f.value = call.Args[1]
+		}
+	}
+
+	params := sig.Params()
+	if params.Len() == 0 || len(call.Args) == 0 || !types.Identical(params.At(0).Type(), types.Typ[types.String]) {
+		return f, true
+	}
+	if f.key, ok = stringConstant(c.pass.TypesInfo, call.Args[0]); ok {
+		f.keyExpr = call.Args[0]
+	}
+	return f, true
+}
+
+// key reports keys that are not snake_case, with a fix for keys given as
+// literals.
+func (c *checker) key(key string, e ast.Expr) {
+	if snakeCase.MatchString(key) {
+		return
+	}
+
+	d := analysis.Diagnostic{
+		Pos:     e.Pos(),
+		End:     e.End(),
+		Message: fmt.Sprintf("key %q is not snake_case", key),
+	}
+	lit, ok := e.(*ast.BasicLit)
+	if s := toSnakeCase(key); ok && snakeCase.MatchString(s) {
+		d.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   fmt.Sprintf("Rename to %q", s),
+			TextEdits: []analysis.TextEdit{{Pos: lit.Pos(), End: lit.End(), NewText: []byte(strconv.Quote(s))}},
+		}}
+	}
+	c.pass.Report(d)
+}
+
+// toSnakeCase converts camelCase, kebab-case and dotted keys to snake_case,
+// keeping acronyms together: userID and HTTPStatus become user_id and
+// http_status.
+func toSnakeCase(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])) {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		case unicode.IsLower(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+
+	parts := strings.FieldsFunc(sb.String(), func(r rune) bool { return r == '_' })
+	return strings.Join(parts, "_")
+}
+
+// duplicate reports a key given twice, with the fix of removing the field
+// repeating it: up to the next argument, or from the end of the one before
+// if it's the last.
+func (c *checker) duplicate(key string, e ast.Expr, first, from, to token.Pos) {
+	c.pass.Report(analysis.Diagnostic{
+		Pos:     e.Pos(),
+		End:     e.End(),
+		Message: fmt.Sprintf("duplicate key %q, also given at line %d", key, c.pass.Fset.Position(first).Line),
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   "Remove the duplicate field",
+			TextEdits: []analysis.TextEdit{{Pos: from, End: to}},
+		}},
+	})
+}
+
+// value reports values of sensitive types.
+func (c *checker) value(e ast.Expr) {
+	t := c.pass.TypesInfo.TypeOf(e)
+	if t == nil {
+		return
+	}
+	name, ok := c.sensitiveType(t)
+	if !ok {
+		return
+	}
+	c.pass.Report(analysis.Diagnostic{
+		Pos:     e.Pos(),
+		End:     e.End(),
+		Message: fmt.Sprintf("logging a %s, which may hold secrets, log the fields needed instead", name),
+	})
+}
+
+// sensitiveType returns the name of the sensitive type of t, if any.
+func (c *checker) sensitiveType(t types.Type) (string, bool) {
+	for {
+		switch u := types.Unalias(t).(type) {
+		case *types.Pointer:
+			t = u.Elem()
+			continue
+		case *types.Slice:
+			t = u.Elem()
+			continue
+		case *types.Array:
+			t = u.Elem()
+			continue
+		case *types.Map:
+			t = u.Elem()
+			continue
+		case *types.Named:
+			name := pkgPath(u.Obj()) + "." + u.Obj().Name()
+			return name, c.sensitive[name]
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,397 @@
+//go:build go1.22
+// +build go1.22
+
+// Package logcheck defines an analyzer reporting mistakes in the logging calls
+// made through this module, such as logging with a context that holds no
+// logger, or with duplicated keys:
+//
+//	log.Info(context.Background(), fmt.Sprintf("user %s logged in", id),
+//		zap.String("userID", id), zap.String("userID", other))
+//
+// Run it with go vet, through the logcheck command:
+//
+//	go install github.com/emiguens/zapfmt/cmd/logcheck
+//	go vet -vettool=$(which logcheck) ./...
+package logcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const doc = `check logging calls made through github.com/emiguens/zapfmt
+
+The logcheck analyzer reports:
+  - entries logged with context.Background() or context.TODO(), which hold no
+    logger, so DefaultLogger writes them without the fields of the request;
+  - keys given twice in one call;
+  - keys that are not snake_case;
+  - values of sensitive types, such as *http.Request, given to zap.Any,
+    zap.Reflect or as key value pairs, which write all of their fields;
+  - messages and templates that are not constant, which can't be grouped
+    or searched for.`
+
+// Analyzer reports mistakes in logging calls.
+var Analyzer = &analysis.Analyzer{
+	Name:     "logcheck",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// SensitiveTypes are the types reported when logged with zap.Any or
+// zap.Reflect, as package path and type name. Pointers to them, and slices
+// and maps of them, are reported too.
+var SensitiveTypes = []string{
+	"crypto/ecdsa.PrivateKey",
+	"crypto/ed25519.PrivateKey",
+	"crypto/rsa.PrivateKey",
+	"crypto/tls.Certificate",
+	"crypto/tls.Config",
+	"net/http.Cookie",
+	"net/http.Header",
+	"net/http.Request",
+	"net/url.Userinfo",
+}
+
+var sensitive string
+
+func init() {
+	Analyzer.Flags.StringVar(&sensitive, "sensitive", "", "comma separated list of more sensitive `types`, such as example.com/auth.Token")
+}
+
+const (
+	logPath     = "github.com/emiguens/zapfmt"
+	zapPath     = "go.uber.org/zap"
+	zapcorePath = "go.uber.org/zap/zapcore"
+)
+
+// signature tells where the arguments of a logging function are, -1 if it
+// doesn't take them.
+type signature struct {
+	ctx    int
+	msg    int
+	fields int
+
+	// pairs is whether fields are key value pairs, as for Infow, and format
+	// whether msg is a template, as for Infof.
+	pairs  bool
+	format bool
+}
+
+// functions are the logging functions of the package, and methods those of
+// its Logger.
+var (
+	functions = map[string]signature{
+		"Check":  {ctx: 0, msg: 2, fields: -1},
+		"With":   {ctx: -1, msg: -1, fields: 1},
+		"Debugf": {ctx: 0, msg: 1, fields: -1, format: true},
+		"Infof":  {ctx: 0, msg: 1, fields: -1, format: true},
+		"Warnf":  {ctx: 0, msg: 1, fields: -1, format: true},
+		"Errorf": {ctx: 0, msg: 1, fields: -1, format: true},
+		"Debugw": {ctx: 0, msg: 1, fields: 2, pairs: true},
+		"Infow":  {ctx: 0, msg: 1, fields: 2, pairs: true},
+		"Warnw":  {ctx: 0, msg: 1, fields: 2, pairs: true},
+		"Errorw": {ctx: 0, msg: 1, fields: 2, pairs: true},
+		"DPanic": {ctx: 0, msg: 1, fields: 2},
+		"Debug":  {ctx: 0, msg: 1, fields: 2},
+		"Error":  {ctx: 0, msg: 1, fields: 2},
+		"Fatal":  {ctx: 0, msg: 1, fields: 2},
+		"Info":   {ctx: 0, msg: 1, fields: 2},
+		"Panic":  {ctx: 0, msg: 1, fields: 2},
+		"Warn":   {ctx: 0, msg: 1, fields: 2},
+	}
+	methods = map[string]signature{
+		"Check":  {ctx: -1, msg: 1, fields: -1},
+		"With":   {ctx: -1, msg: -1, fields: 0},
+		"DPanic": {ctx: -1, msg: 0, fields: 1},
+		"Debug":  {ctx: -1, msg: 0, fields: 1},
+		"Error":  {ctx: -1, msg: 0, fields: 1},
+		"Fatal":  {ctx: -1, msg: 0, fields: 1},
+		"Info":   {ctx: -1, msg: 0, fields: 1},
+		"Panic":  {ctx: -1, msg: 0, fields: 1},
+		"Warn":   {ctx: -1, msg: 0, fields: 1},
+	}
+)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	c := &checker{pass: pass, sensitive: make(map[string]bool)}
+	for _, t := range SensitiveTypes {
+		c.sensitive[t] = true
+	}
+	for _, t := range strings.Split(sensitive, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			c.sensitive[t] = true
+		}
+	}
+
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	inspect.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if push {
+			c.call(n.(*ast.CallExpr), stack)
+		}
+		return true
+	})
+	return nil, nil
+}
+
+type checker struct {
+	pass      *analysis.Pass
+	sensitive map[string]bool
+}
+
+// call checks a call if it's to a logging function. stack holds the nodes
+// enclosing it.
+func (c *checker) call(call *ast.CallExpr, stack []ast.Node) {
+	fn, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+	if !ok || pkgPath(fn) != logPath {
+		return
+	}
+
+	sig, name := signature{}, "log."+fn.Name()
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		if !isNamed(recv.Type(), logPath, "Logger") {
+			return
+		}
+		sig, ok = methods[fn.Name()]
+		name = "Logger." + fn.Name()
+	} else {
+		sig, ok = functions[fn.Name()]
+	}
+	if !ok {
+		return
+	}
+
+	if sig.ctx >= 0 && sig.msg >= 0 && sig.ctx < len(call.Args) {
+		c.context(call, name, call.Args[sig.ctx], stack)
+	}
+	if sig.msg >= 0 && sig.msg < len(call.Args) {
+		c.message(call, fn, sig)
+	}
+	if sig.fields >= 0 && sig.fields < len(call.Args) && !call.Ellipsis.IsValid() {
+		c.fields(call.Args, sig.fields, sig.pairs)
+	}
+}
+
+// context reports logging with a context holding no logger.
+func (c *checker) context(call *ast.CallExpr, name string, ctx ast.Expr, stack []ast.Node) {
+	bg := c.background(ctx, stack)
+	if bg == "" {
+		return
+	}
+
+	d := analysis.Diagnostic{
+		Pos:     ctx.Pos(),
+		End:     ctx.End(),
+		Message: fmt.Sprintf("%s called with %s, which holds no logger, so DefaultLogger is used", name, bg),
+	}
+	if param := c.contextParam(stack); param != "" {
+		d.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   "Use " + param,
+			TextEdits: []analysis.TextEdit{{Pos: ctx.Pos(), End: ctx.End(), NewText: []byte(param)}},
+		}}
+	}
+	c.pass.Report(d)
+}
+
+// background returns the function making the given context if it's
+// context.Background() or context.TODO(), either called in place or
+// assigned to a local variable, or "" otherwise.
+func (c *checker) background(ctx ast.Expr, stack []ast.Node) string {
+	ctx = ast.Unparen(ctx)
+	if call, ok := ctx.(*ast.CallExpr); ok {
+		fn, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+		if ok && fn.Pkg() != nil && fn.Pkg().Path() == "context" && (fn.Name() == "Background" || fn.Name() == "TODO") {
+			return "context." + fn.Name() + "()"
+		}
+		return ""
+	}
+
+	id, ok := ctx.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	v, ok := c.pass.TypesInfo.Uses[id].(*types.Var)
+	if !ok || v.Parent() == nil || v.Parent() == v.Pkg().Scope() {
+		return ""
+	}
+
+	// The variable is local: it's a background context if every value
+	// assigned to it is.
+	body := enclosingBody(stack)
+	if body == nil {
+		return ""
+	}
+	bg := ""
+	ast.Inspect(body, func(n ast.Node) bool {
+		var lhs, rhs []ast.Expr
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			lhs, rhs = n.Lhs, n.Rhs
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				lhs = append(lhs, name)
+			}
+			rhs = n.Values
+		case *ast.UnaryExpr:
+			// The variable may be assigned through its address.
+			if x, ok := ast.Unparen(n.X).(*ast.Ident); ok && n.Op == token.AND && c.pass.TypesInfo.Uses[x] == v {
+				bg = "-"
+			}
+			return true
+		default:
+			return true
+		}
+		for i, l := range lhs {
+			id, ok := l.(*ast.Ident)
+			if !ok || c.pass.TypesInfo.ObjectOf(id) != v {
+				continue
+			}
+			if len(rhs) != len(lhs) {
+				bg = "-"
+				continue
+			}
+			b := c.background(rhs[i], nil)
+			if b == "" || bg != "" && bg != b {
+				bg = "-"
+			} else if bg == "" {
+				bg = b
+			}
+		}
+		return true
+	})
+	if bg == "-" {
+		return ""
+	}
+	return bg
+}
+
+// enclosingBody returns the body of the outermost function enclosing a node.
+func enclosingBody(stack []ast.Node) *ast.BlockStmt {
+	for _, n := range stack {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			return n.Body
+		case *ast.FuncLit:
+			return n.Body
+		}
+	}
+	return nil
+}
+
+// contextParam returns the name of a context.Context parameter of the
+// functions enclosing a node, innermost first, or "" if none has one.
+func (c *checker) contextParam(stack []ast.Node) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var ft *ast.FuncType
+		switch n := stack[i].(type) {
+		case *ast.FuncDecl:
+			ft = n.Type
+		case *ast.FuncLit:
+			ft = n.Type
+		default:
+			continue
+		}
+		for _, field := range ft.Params.List {
+			if !isNamed(c.pass.TypesInfo.TypeOf(field.Type), "context", "Context") {
+				continue
+			}
+			for _, name := range field.Names {
+				if name.Name != "_" {
+					return name.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// message reports messages and templates that are not constant. When the
+// message is formatted with fmt.Sprintf, the fix is calling the formatting
+// function of the same level.
+func (c *checker) message(call *ast.CallExpr, fn *types.Func, sig signature) {
+	msg := call.Args[sig.msg]
+	if c.pass.TypesInfo.Types[msg].Value != nil {
+		return
+	}
+
+	if sig.format {
+		d := analysis.Diagnostic{
+			Pos:     msg.Pos(),
+			End:     msg.End(),
+			Message: fmt.Sprintf("non-constant template in call to log.%s", fn.Name()),
+		}
+		if len(call.Args) == sig.msg+1 {
+			d.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   `Insert "%s" template`,
+				TextEdits: []analysis.TextEdit{{Pos: msg.Pos(), End: msg.Pos(), NewText: []byte(`"%s", `)}},
+			}}
+		}
+		c.pass.Report(d)
+		return
+	}
+
+	d := analysis.Diagnostic{
+		Pos:     msg.Pos(),
+		End:     msg.End(),
+		Message: "non-constant message, give the values that change as fields",
+	}
+	formatted, hasFormatted := functions[fn.Name()+"f"]
+	sprintf, ok := ast.Unparen(msg).(*ast.CallExpr)
+	if ok && hasFormatted && sig.ctx >= 0 && len(call.Args) == sig.msg+1 && isFunc(c.pass.TypesInfo, sprintf, "fmt", "Sprintf") {
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && formatted.msg == sig.msg && !sprintf.Ellipsis.IsValid() {
+			d.SuggestedFixes = []analysis.SuggestedFix{{
+				Message: fmt.Sprintf("Use log.%sf", fn.Name()),
+				TextEdits: []analysis.TextEdit{
+					{Pos: sel.Sel.End(), End: sel.Sel.End(), NewText: []byte("f")},
+					{Pos: msg.Pos(), End: sprintf.Lparen + 1},
+					{Pos: sprintf.Rparen, End: msg.End()},
+				},
+			}}
+		}
+	}
+	c.pass.Report(d)
+}
+
+// pkgPath returns the path of the package of an object, without the vendor
+// directory it may be in.
+func pkgPath(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return ""
+	}
+	path := obj.Pkg().Path()
+	if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+		path = path[i+len("/vendor/"):]
+	}
+	return path
+}
+
+// isNamed reports whether t is the named type path.name.
+func isNamed(t types.Type, path, name string) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	return ok && n.Obj().Name() == name && pkgPath(n.Obj()) == path
+}
+
+// isFunc reports whether call calls the function path.name.
+func isFunc(info *types.Info, call *ast.CallExpr, path, name string) bool {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	return ok && fn.Name() == name && pkgPath(fn) == path
+}
+
+// stringConstant returns the value of a constant string expression.
+func stringConstant(info *types.Info, e ast.Expr) (string, bool) {
+	v := info.Types[e].Value
+	if v == nil || v.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(v), true
+}
@@ -0,0 +1,18 @@
+//go:build go1.22
+// +build go1.22
+
+package logcheck_test
+
+import (
+	"testing"
+
+	"github.com/emiguens/zapfmt/logcheck"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	if err := logcheck.Analyzer.Flags.Set("sensitive", "a.Credentials"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), logcheck.Analyzer, "a")
+}
@@ -0,0 +1,82 @@
+package a
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"go.uber.org/zap"
+)
+
+type Credentials struct {
+	User, Password string
+}
+
+func background() {
+	log.Debug(context.Background(), "starting") // want `log.Debug called with context.Background\(\), which holds no logger, so DefaultLogger is used`
+	log.Info(context.TODO(), "started")         // want `log.Info called with context.TODO\(\), which holds no logger`
+
+	ctx := context.Background()
+	log.Info(ctx, "started") // want `log.Info called with context.Background\(\)`
+	log.With(ctx, zap.String("user_id", "1"))
+}
+
+func handle(ctx context.Context, id string) {
+	log.Info(context.Background(), "handling") // want `log.Info called with context.Background\(\)`
+	go func() {
+		log.Info(context.TODO(), "done") // want `log.Info called with context.TODO\(\)`
+	}()
+	log.Info(ctx, "handled")
+
+	child := context.Background()
+	if id != "" {
+		child = ctx
+	}
+	log.Info(child, "handled")
+}
+
+func keys(ctx context.Context, l log.Logger, key string) {
+	log.Info(ctx, "request",
+		zap.String("user_id", "1"),
+		zap.String("userID", "1"),  // want `key "userID" is not snake_case`
+		zap.Int("HTTPStatus", 200), // want `key "HTTPStatus" is not snake_case`
+		zap.String("span-id", "1"), // want `key "span-id" is not snake_case`
+		zap.Duration("user_id", 0), // want `duplicate key "user_id", also given at line 42`
+		zap.String(key, "not checked"),
+		zap.String(key, "not checked"),
+	)
+
+	l.With(zap.Error(nil), zap.NamedError("cause", nil), zap.Error(nil)) // want `duplicate key "error", also given at line 51`
+	l.Info("request", zap.String("id", "1"), zap.Namespace("http"), zap.String("id", "2"))
+
+	const userKey = "User"
+	l.Info("request", zap.String(userKey, "1")) // want `key "User" is not snake_case`
+
+	log.Infow(ctx, "request", "id", 1, zap.String("name", "a"), "id", 2, "Name", "b") // want `duplicate key "id", also given at line 57` `key "Name" is not snake_case`
+	log.Infow(ctx, "request", 1, 2, "id", 3, "id", 4)
+	log.Info(ctx, "request", []zap.Field{zap.String("id", "1"), zap.String("id", "2")}...)
+}
+
+func sensitive(ctx context.Context, u *url.URL, users []*url.Userinfo, creds Credentials) {
+	log.Info(ctx, "request", zap.Any("user", u.User))       // want `logging a net/url.Userinfo, which may hold secrets`
+	log.Info(ctx, "request", zap.Reflect("users", users))   // want `logging a net/url.Userinfo`
+	log.Info(ctx, "request", zap.Any("credentials", creds)) // want `logging a a.Credentials`
+	log.Infow(ctx, "request", "url", u, "user", u.User)     // want `logging a net/url.Userinfo`
+	log.Info(ctx, "request", zap.Any("url", u))
+}
+
+func messages(ctx context.Context, l log.Logger, id string, d time.Duration) {
+	const msg = "constant"
+	log.Info(ctx, msg)
+	log.Info(ctx, "user "+id)                                              // want `non-constant message, give the values that change as fields`
+	log.Info(ctx, fmt.Sprintf("user %s logged in after %s", id, d))        // want `non-constant message`
+	log.Debug(ctx, (fmt.Sprintf("user %s", id)))                           // want `non-constant message`
+	log.Error(ctx, fmt.Sprintf("user %s", id), zap.Duration("elapsed", d)) // want `non-constant message`
+	l.Info(fmt.Sprintf("user %s", id))                                     // want `non-constant message`
+
+	log.Infof(ctx, "user %s", id)
+	log.Infof(ctx, id)         // want `non-constant template in call to log.Infof`
+	log.Debugf(ctx, id+"!", 1) // want `non-constant template in call to log.Debugf`
+}
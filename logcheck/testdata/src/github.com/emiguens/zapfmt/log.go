@@ -0,0 +1,25 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Logger interface {
+	With(fields ...zap.Field) Logger
+	Info(msg string, fields ...zap.Field)
+	Check(lvl zapcore.Level, msg string) interface{}
+}
+
+func With(ctx context.Context, fields ...zap.Field) context.Context { return ctx }
+
+func Debug(ctx context.Context, msg string, fields ...zap.Field) {}
+func Info(ctx context.Context, msg string, fields ...zap.Field)  {}
+func Error(ctx context.Context, msg string, fields ...zap.Field) {}
+
+func Debugf(ctx context.Context, template string, args ...interface{}) {}
+func Infof(ctx context.Context, template string, args ...interface{})  {}
+
+func Infow(ctx context.Context, msg string, keysAndValues ...interface{}) {}
@@ -0,0 +1,21 @@
+package zap
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type Field = zapcore.Field
+
+func Any(key string, value interface{}) Field     { return Field{Key: key, Interface: value} }
+func Reflect(key string, value interface{}) Field { return Field{Key: key, Interface: value} }
+func String(key string, value string) Field       { return Field{Key: key} }
+func Int(key string, value int) Field             { return Field{Key: key} }
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key}
+}
+func Error(err error) Field                  { return Field{Key: "error"} }
+func NamedError(key string, err error) Field { return Field{Key: key} }
+func Namespace(key string) Field             { return Field{Key: key} }
+func Skip() Field                            { return Field{} }
@@ -0,0 +1,8 @@
+package zapcore
+
+type Level int8
+
+type Field struct {
+	Key       string
+	Interface interface{}
+}
@@ -24,10 +24,35 @@ var DefaultLogger Logger = &logger{
 //
 // It uses the custom Key Value encoder, writes to standard error, and enables sampling.
 // Stacktraces are automatically included on logs of ErrorLevel and above.
-func NewProductionLogger(lvl *zap.AtomicLevel) Logger {
-	zapCore := newZapCoreAtLevel(zap.DebugLevel)
+// Timestamps are written in UTC with microsecond precision, unless changed
+// through WithTimeEncoding and WithTimeZone.
+func NewProductionLogger(lvl *zap.AtomicLevel, opts ...Option) Logger {
+	cfg := newConfig(opts)
+	return newLogger(newZapCoreAtLevel(zap.DebugLevel, cfg), lvl, cfg)
+}
+
+// New returns a logger writing to the given core at the given level and
+// above, configured like the production logger. The core should be enabled
+// at Debug level, as the logger can only restrict the levels it allows.
+//
+// It's meant for packages plugging their own cores, such as logtest.
+func New(core zapcore.Core, lvl *zap.AtomicLevel, opts ...Option) Logger {
+	return newLogger(core, lvl, newConfig(opts))
+}
+
+func newLogger(core zapcore.Core, lvl *zap.AtomicLevel, cfg *config) Logger {
+	for _, wrap := range cfg.wrappers {
+		core = wrap(core)
+	}
+	if cfg.clock != nil {
+		core = &clockCore{
+			Core:  core,
+			clock: cfg.clock,
+		}
+	}
+
 	l := zap.New(
-		zapCore,
+		core,
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
 		zap.AddStacktrace(zap.ErrorLevel),
@@ -50,7 +75,10 @@ type logger struct {
 	*zap.Logger
 }
 
-var _ Logger = &logger{}
+var (
+	_ Logger     = &logger{}
+	_ lazyLogger = &logger{}
+)
 
 // WithLevel creates a child logger that logs on the given level.
 // Child logger contains all fields from the parent.
@@ -71,6 +99,16 @@ func (l *logger) With(fields ...zapcore.Field) Logger {
 	}
 }
 
+// WithLazy creates a child logger like With, but its fields are encoded,
+// and the values of lazy fields computed, only once the child writes an
+// entry, for children that may never log.
+func (l *logger) WithLazy(fields ...zapcore.Field) Logger {
+	child := l.Logger.With(lazyFields(fields)...)
+	return &logger{
+		Logger: child,
+	}
+}
+
 // Named adds a new path segment to the logger's name. Segments are joined by
 // periods. By default, Loggers are unnamed.
 func (l *logger) Named(s string) Logger {
@@ -80,8 +118,34 @@ func (l *logger) Named(s string) Logger {
 	}
 }
 
-func newZapCoreAtLevel(lvl zapcore.Level) zapcore.Core {
-	encoderConfig := zapcore.EncoderConfig{
+func newZapCoreAtLevel(lvl zapcore.Level, cfg *config) zapcore.Core {
+	encoderConfig := NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = cfg.timeEncoder()
+
+	encoderOptions := cfg.encoderOptions
+	if cfg.metrics != nil {
+		encoderOptions = append([]encoders.Option{encoders.WithTruncatedCounter(cfg.metrics.Truncated())}, encoderOptions...)
+	}
+
+	encoder := encoders.NewKeyValueEncoder(encoderConfig, encoderOptions...)
+	if cfg.metrics != nil {
+		encoder = cfg.metrics.WrapEncoder(encoder)
+	}
+	writer := zapcore.Lock(zapcore.AddSync(os.Stderr))
+
+	core := zapcore.NewCore(encoder, writer, lvl)
+	if cfg.metrics != nil {
+		core = cfg.metrics.WrapCore(core)
+	}
+	return core
+}
+
+// NewProductionEncoderConfig returns the encoder configuration used by the
+// production logger: ts, level, logger, caller, msg and stacktrace keys,
+// lowercase levels, RFC3339 timestamps with microseconds, durations in
+// seconds and short callers.
+func NewProductionEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
 		TimeKey:        "ts",
 		LevelKey:       "level",
 		NameKey:        "logger",
@@ -94,17 +158,12 @@ func newZapCoreAtLevel(lvl zapcore.Level) zapcore.Core {
 		EncodeDuration: zapcore.SecondsDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
-
-	encoder := encoders.NewKeyValueEncoder(encoderConfig)
-	writer := zapcore.Lock(zapcore.AddSync(os.Stderr))
-
-	return zapcore.NewCore(encoder, writer, lvl)
 }
 
+const rfc3339Micro = "2006-01-02T15:04:05.000000Z07:00"
+
 // rfc3399NanoTimeEncoder serializes a time.Time to an RFC3399-formatted string
 // with microsecond precision padded with zeroes to make it fixed width.
 func rfc3399NanoTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	const RFC3339Micro = "2006-01-02T15:04:05.000000Z07:00"
-
-	enc.AppendString(t.UTC().Format(RFC3339Micro))
+	enc.AppendString(t.UTC().Format(rfc3339Micro))
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
 	"github.com/kami-zh/go-capturer"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -306,3 +307,15 @@ func TestKeyValueLogger(t *testing.T) {
 	}
 
 }
+
+func TestWriteErrors(t *testing.T) {
+	core := zapcore.NewCore(encoders.NewKeyValueEncoder(log.NewProductionEncoderConfig()), failingWriter{}, zap.DebugLevel)
+	lvl := zap.NewAtomicLevelAt(zap.InfoLevel)
+
+	out := capturer.CaptureStderr(func() {
+		log.New(core, &lvl).Info("request received")
+	})
+	if n := strings.Count(out, "write error: disk full"); n != 1 {
+		t.Fatalf("expected the write error to be reported once, got:\n%s", out)
+	}
+}
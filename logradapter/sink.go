@@ -0,0 +1,108 @@
+// Package logradapter implements a logr.LogSink writing through the loggers
+// of this package, for libraries logging through github.com/go-logr/logr.
+//
+//	logger := logradapter.New(log.NewProductionLogger(&lvl))
+//	logger.V(1).Info("reconciling", "object", name)
+package logradapter
+
+import (
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/internal/caller"
+	"github.com/emiguens/zapfmt/internal/kv"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New returns a logr.Logger writing through the given logger.
+func New(l log.Logger) logr.Logger {
+	return logr.New(NewLogSink(l))
+}
+
+// NewLogSink returns a logr.LogSink writing through the given logger.
+//
+// Verbosity 0 is logged at InfoLevel and any greater verbosity at DebugLevel.
+// Names are added to the logger name, and key value pairs are written as
+// fields. zap fields may be given in place of a key value pair, and a key
+// without a value is written as the value of a "!BADKEY" field.
+func NewLogSink(l log.Logger) *LogSink {
+	return &LogSink{logger: l}
+}
+
+// LogSink is a logr.LogSink backed by a Logger.
+type LogSink struct {
+	logger log.Logger
+	depth  int
+}
+
+var (
+	_ logr.LogSink          = &LogSink{}
+	_ logr.CallDepthLogSink = &LogSink{}
+)
+
+// Init receives runtime information from logr. The caller is found by
+// skipping the logr frames, so the information is not needed.
+func (s *LogSink) Init(logr.RuntimeInfo) {}
+
+// Enabled reports whether entries at the given verbosity are logged.
+func (s *LogSink) Enabled(level int) bool {
+	l, ok := s.logger.(interface{ Core() zapcore.Core })
+	if !ok {
+		return true
+	}
+	return l.Core().Enabled(Level(level))
+}
+
+// Info logs a message at the level matching the given verbosity.
+func (s *LogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.write(Level(level), msg, kv.Fields(keysAndValues))
+}
+
+// Error logs a message at ErrorLevel along with the error.
+func (s *LogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.write(zap.ErrorLevel, msg, append(kv.Fields(keysAndValues), zap.Error(err)))
+}
+
+// WithValues returns a sink adding the given key value pairs to every entry.
+func (s *LogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &LogSink{
+		logger: s.logger.With(kv.Fields(keysAndValues)...),
+		depth:  s.depth,
+	}
+}
+
+// WithName returns a sink adding the given name to the logger name.
+func (s *LogSink) WithName(name string) logr.LogSink {
+	return &LogSink{
+		logger: s.logger.Named(name),
+		depth:  s.depth,
+	}
+}
+
+// WithCallDepth returns a sink attributing entries to the code depth frames
+// above the caller of the logr.Logger methods.
+func (s *LogSink) WithCallDepth(depth int) logr.LogSink {
+	return &LogSink{
+		logger: s.logger,
+		depth:  s.depth + depth,
+	}
+}
+
+func (s *LogSink) write(lvl zapcore.Level, msg string, fields []zapcore.Field) {
+	ce := s.logger.Check(lvl, msg)
+	if ce == nil {
+		return
+	}
+	if ce.Entry.Caller.Defined {
+		ce.Entry.Caller = caller.Find(s.depth, "github.com/go-logr/logr.", "github.com/emiguens/zapfmt/logradapter.")
+	}
+	ce.Write(fields...)
+}
+
+// Level maps a logr verbosity to a zap level.
+func Level(verbosity int) zapcore.Level {
+	if verbosity <= 0 {
+		return zap.InfoLevel
+	}
+	return zap.DebugLevel
+}
@@ -0,0 +1,100 @@
+package logradapter_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/emiguens/zapfmt/logradapter"
+	"github.com/emiguens/zapfmt/logtest"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogSink(t *testing.T) {
+	tt := []struct {
+		Name       string
+		SetupFunc  func(l logr.Logger)
+		AssertFunc func(t *testing.T, entries []logtest.Entry)
+	}{
+		{
+			Name: "Verbosity",
+			SetupFunc: func(l logr.Logger) {
+				l.Info("my Info message")
+				l.V(1).Info("my Debug message")
+				l.V(2).Info("my verbose message")
+			},
+			AssertFunc: func(t *testing.T, entries []logtest.Entry) {
+				if len(entries) != 1 || entries[0].Level != zap.InfoLevel {
+					t.Fatalf("expected only the info entry, got: %v", entries)
+				}
+			},
+		},
+		{
+			Name: "Error",
+			SetupFunc: func(l logr.Logger) {
+				l.Error(errors.New("my error"), "my Error message", "attempt", 3)
+			},
+			AssertFunc: func(t *testing.T, entries []logtest.Entry) {
+				e := entries[0]
+				if e.Level != zap.ErrorLevel || e.Message != "my Error message" {
+					t.Fatalf("unexpected entry: %+v", e.Entry)
+				}
+				fields := e.FieldMap()
+				if fields["error"] != "my error" || fields["attempt"] != int64(3) {
+					t.Fatalf("unexpected fields: %v", fields)
+				}
+			},
+		},
+		{
+			Name: "Names And Values",
+			SetupFunc: func(l logr.Logger) {
+				l.WithName("controller").WithName("pods").WithValues("namespace", "default").
+					Info("my Info message", "name", "web", zap.Int("replicas", 2), 42, "answer", "dangling")
+			},
+			AssertFunc: func(t *testing.T, entries []logtest.Entry) {
+				e := entries[0]
+				if e.LoggerName != "controller.pods" {
+					t.Fatalf("expected logger name controller.pods, got: %s", e.LoggerName)
+				}
+				if ctx := e.ContextMap(); ctx["namespace"] != "default" {
+					t.Fatalf("unexpected context fields: %v", ctx)
+				}
+				fields := e.FieldMap()
+				if fields["name"] != "web" || fields["replicas"] != int64(2) || fields["42"] != "answer" || fields["!BADKEY"] != "dangling" {
+					t.Fatalf("unexpected fields: %v", fields)
+				}
+			},
+		},
+		{
+			Name: "Caller",
+			SetupFunc: func(l logr.Logger) {
+				l.Info("my Info message")
+				helper(l.WithCallDepth(1))
+			},
+			AssertFunc: func(t *testing.T, entries []logtest.Entry) {
+				for _, e := range entries {
+					if file := filepath.Base(e.Caller.File); file != "sink_test.go" {
+						t.Fatalf("expected caller in sink_test.go, got: %s", e.Caller.File)
+					}
+				}
+				if entries[0].Caller.Line != entries[1].Caller.Line-1 {
+					t.Fatalf("expected helper entry to be attributed to its caller, got lines %d and %d", entries[0].Caller.Line, entries[1].Caller.Line)
+				}
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			logger, logs := logtest.New(zapcore.InfoLevel)
+			tc.SetupFunc(logradapter.New(logger))
+			tc.AssertFunc(t, logs.All())
+		})
+	}
+}
+
+func helper(l logr.Logger) {
+	l.Info("my helper message")
+}
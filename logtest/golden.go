@@ -0,0 +1,126 @@
+package logtest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+)
+
+var (
+	// Update makes golden assertions write the golden files instead of
+	// comparing against them. It's set when the LOGTEST_UPDATE environment
+	// variable isn't empty, and may also be set by tests, e.g. from their own
+	// flag.
+	Update = os.Getenv("LOGTEST_UPDATE") != ""
+
+	// GoldenTime is the time of every entry logged by a golden logger.
+	GoldenTime = time.Date(2019, time.April, 8, 20, 21, 32, 375067000, time.UTC)
+
+	// GoldenCaller is the caller of every entry logged by a golden logger.
+	GoldenCaller = zapcore.NewEntryCaller(0, "zapfmt/caller.go", 1, true)
+
+	// GoldenStacktrace replaces the stacktrace of every entry logged by a
+	// golden logger that has one.
+	GoldenStacktrace = "<stacktrace>"
+)
+
+// Golden captures the key value encoded output of a logger, to be compared
+// against a golden file.
+type Golden struct {
+	t   testing.TB
+	buf zaptest.Buffer
+}
+
+// NewGolden returns a logger writing the entries at the given level and above
+// the way the production logger does, except that entries are timestamped by
+// a clock stopped at GoldenTime, and the caller and stacktrace are replaced by
+// GoldenCaller and GoldenStacktrace so that the output is stable between runs.
+func NewGolden(t testing.TB, lvl zapcore.Level, opts ...encoders.Option) (log.Logger, *Golden) {
+	g := &Golden{t: t}
+
+	encoder := encoders.NewKeyValueEncoder(log.NewProductionEncoderConfig(), opts...)
+	core := zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(&g.buf)), zap.DebugLevel)
+	atomicLevel := zap.NewAtomicLevelAt(lvl)
+
+	return log.New(&goldenCore{Core: core}, &atomicLevel, log.WithClock(NewClock(GoldenTime))), g
+}
+
+// String returns the output captured so far.
+func (g *Golden) String() string {
+	return g.buf.String()
+}
+
+// Assert compares the captured output against testdata/<name>.golden, where
+// name is the test name with slashes replaced by underscores. When Update is
+// set the file is written instead.
+func (g *Golden) Assert() {
+	g.t.Helper()
+	name := strings.Replace(g.t.Name(), "/", "_", -1)
+	AssertGolden(g.t, name, g.buf.Bytes())
+}
+
+// AssertGolden compares got against testdata/<name>.golden, failing the test
+// if they differ. When Update is set the file is written instead.
+func AssertGolden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+
+	if Update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatalf("creating testdata directory: %v", err)
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run tests with LOGTEST_UPDATE=1 to create it): %v", err)
+	}
+
+	if !bytes.Equal(expected, got) {
+		t.Fatalf("output doesn't match %s\nexpected:\n%s\ngot:\n%s", path, expected, got)
+	}
+}
+
+// goldenCore replaces the caller and stacktrace of entries before writing
+// them to the wrapped core.
+//
+// The stacktrace is only known once the entry has been checked, which is why
+// goldenCore adds itself to the checked entry instead of delegating Check.
+type goldenCore struct {
+	zapcore.Core
+}
+
+func (c *goldenCore) With(fields []zapcore.Field) zapcore.Core {
+	return &goldenCore{Core: c.Core.With(fields)}
+}
+
+func (c *goldenCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *goldenCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Caller.Defined {
+		ent.Caller = GoldenCaller
+	}
+	if ent.Stack != "" {
+		ent.Stack = GoldenStacktrace
+	}
+	return c.Core.Write(ent, fields)
+}
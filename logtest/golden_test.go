@@ -0,0 +1,58 @@
+package logtest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/logtest"
+	"go.uber.org/zap"
+)
+
+func TestGolden(t *testing.T) {
+	tt := []struct {
+		Name      string
+		Options   []encoders.Option
+		SetupFunc func(l log.Logger)
+	}{
+		{
+			Name: "Levels",
+			SetupFunc: func(l log.Logger) {
+				l.Debug("my Debug message")
+				l.Info("my Info message")
+				l.Warn("my Warn message")
+				l.Error("my Error message")
+			},
+		},
+		{
+			Name: "Fields",
+			SetupFunc: func(l log.Logger) {
+				l.Named("first_level").With(zap.String("request_id", "abc")).Info("my Info message",
+					zap.Time("time_key", time.Unix(0, 0)),
+					zap.Int64("int64_key", 1234),
+					zap.Float64("float64_key", 1234.5678),
+					zap.Duration("duration_key", 374*time.Millisecond),
+					zap.Strings("strings_key", []string{"a", "b"}),
+					zap.Error(errors.New("my error")),
+				)
+			},
+		},
+		{
+			Name:    "Encoder Options",
+			Options: []encoders.Option{encoders.WithDedupe(encoders.KeepLast), encoders.WithSortedFields()},
+			SetupFunc: func(l log.Logger) {
+				l.With(zap.String("request_id", "abc"), zap.Int("status", 0)).Info("request done", zap.Int("status", 200))
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			l, golden := logtest.NewGolden(t, zap.DebugLevel, tc.Options...)
+			tc.SetupFunc(l)
+			golden.Assert()
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package logtest provides a logger that records entries in memory, so tests
+// can assert on what was logged instead of parsing the encoded output.
+//
+//	ctx, logs := logtest.NewContext(context.Background(), zap.DebugLevel)
+//	handle(ctx, req)
+//
+//	if logs.FilterLevel(zap.ErrorLevel).Len() != 0 {
+//		t.Fatalf("unexpected errors: %v", logs.FilterLevel(zap.ErrorLevel).All())
+//	}
+package logtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	log "github.com/emiguens/zapfmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is an entry recorded by the test logger.
+type Entry struct {
+	zapcore.Entry
+
+	// Fields holds the fields given at the log site.
+	Fields []zapcore.Field
+
+	// Context holds the fields added to the logger through With.
+	Context []zapcore.Field
+}
+
+// FieldMap returns the fields given at the log site as a map.
+func (e Entry) FieldMap() map[string]interface{} {
+	return fieldMap(e.Fields)
+}
+
+// ContextMap returns the fields added through With as a map.
+func (e Entry) ContextMap() map[string]interface{} {
+	return fieldMap(e.Context)
+}
+
+// Field returns the last field with the given key, looking first at the log
+// site fields and then at the context ones.
+func (e Entry) Field(key string) (zapcore.Field, bool) {
+	for _, fields := range [][]zapcore.Field{e.Fields, e.Context} {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if fields[i].Key == key {
+				return fields[i], true
+			}
+		}
+	}
+	return zapcore.Field{}, false
+}
+
+func fieldMap(fields []zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+// Logs is an in-memory store of recorded entries. All methods are safe for
+// concurrent use.
+type Logs struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// Len returns the number of recorded entries.
+func (l *Logs) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.entries)
+}
+
+// All returns a copy of all the recorded entries.
+func (l *Logs) All() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]Entry, len(l.entries))
+	copy(entries, l.entries)
+	return entries
+}
+
+// TakeAll returns all the recorded entries and removes them from the store.
+func (l *Logs) TakeAll() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := l.entries
+	l.entries = nil
+	return entries
+}
+
+// FilterLevel returns the entries logged at the given level.
+func (l *Logs) FilterLevel(lvl zapcore.Level) *Logs {
+	return l.filter(func(e Entry) bool {
+		return e.Level == lvl
+	})
+}
+
+// FilterMessage returns the entries with the given message.
+func (l *Logs) FilterMessage(msg string) *Logs {
+	return l.filter(func(e Entry) bool {
+		return e.Message == msg
+	})
+}
+
+// FilterMessageSnippet returns the entries whose message contains snippet.
+func (l *Logs) FilterMessageSnippet(snippet string) *Logs {
+	return l.filter(func(e Entry) bool {
+		return strings.Contains(e.Message, snippet)
+	})
+}
+
+// FilterField returns the entries having the given field, either given at the
+// log site or added through With.
+func (l *Logs) FilterField(field zapcore.Field) *Logs {
+	return l.filter(func(e Entry) bool {
+		for _, fields := range [][]zapcore.Field{e.Fields, e.Context} {
+			for _, f := range fields {
+				if f.Equals(field) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+func (l *Logs) filter(match func(Entry) bool) *Logs {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	filtered := &Logs{}
+	for _, e := range l.entries {
+		if match(e) {
+			filtered.entries = append(filtered.entries, e)
+		}
+	}
+	return filtered
+}
+
+func (l *Logs) add(e Entry) {
+	l.mu.Lock()
+	l.entries = append(l.entries, e)
+	l.mu.Unlock()
+}
+
+// New returns a logger recording the entries at the given level and above
+// into the returned store. The logger behaves like the production one: it
+// adds the caller to every entry and a stacktrace to the ones at Error level
+// and above, and its level may be changed with WithLevel.
+func New(lvl zapcore.Level) (log.Logger, *Logs) {
+	logs := &Logs{}
+	atomicLevel := zap.NewAtomicLevelAt(lvl)
+	return log.New(&recordingCore{logs: logs}, &atomicLevel), logs
+}
+
+// NewContext returns a copy of the parent context associated with a new test
+// logger, along with the store of its entries.
+func NewContext(parent context.Context, lvl zapcore.Level) (context.Context, *Logs) {
+	logger, logs := New(lvl)
+	return log.Context(parent, logger), logs
+}
+
+// recordingCore is a core enabled at all levels that records entries into
+// logs, keeping the fields added through With apart.
+type recordingCore struct {
+	logs    *Logs
+	context []zapcore.Field
+}
+
+func (c *recordingCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *recordingCore) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	context = append(context, fields...)
+	return &recordingCore{
+		logs:    c.logs,
+		context: context,
+	}
+}
+
+func (c *recordingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *recordingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// The fields slice may be reused by the caller once Write returns.
+	recorded := make([]zapcore.Field, len(fields))
+	copy(recorded, fields)
+
+	c.logs.add(Entry{
+		Entry:   ent,
+		Fields:  recorded,
+		Context: c.context,
+	})
+	return nil
+}
+
+func (c *recordingCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package logtest_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/logtest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogs(t *testing.T) {
+	tt := []struct {
+		Name       string
+		Level      zapcore.Level
+		SetupFunc  func(ctx context.Context)
+		AssertFunc func(t *testing.T, logs *logtest.Logs)
+	}{
+		{
+			Name:  "Records Entries At Level",
+			Level: zap.InfoLevel,
+			SetupFunc: func(ctx context.Context) {
+				log.Debug(ctx, "my Debug message")
+				log.Info(ctx, "my Info message")
+				log.Warn(ctx, "my Warn message")
+			},
+			AssertFunc: func(t *testing.T, logs *logtest.Logs) {
+				if logs.Len() != 2 {
+					t.Fatalf("expected 2 entries, got: %d", logs.Len())
+				}
+				if n := logs.FilterLevel(zap.WarnLevel).Len(); n != 1 {
+					t.Fatalf("expected 1 warn entry, got: %d", n)
+				}
+				if n := logs.FilterMessage("my Debug message").Len(); n != 0 {
+					t.Fatalf("expected no debug entries, got: %d", n)
+				}
+			},
+		},
+		{
+			Name:  "Records Name Caller And Stacktrace",
+			Level: zap.DebugLevel,
+			SetupFunc: func(ctx context.Context) {
+				ctx = log.Named(ctx, "first_level")
+				log.Error(ctx, "my Error message")
+			},
+			AssertFunc: func(t *testing.T, logs *logtest.Logs) {
+				e := logs.All()[0]
+				if e.LoggerName != "first_level" {
+					t.Fatalf("expected logger name first_level, got: %s", e.LoggerName)
+				}
+				if file := filepath.Base(e.Caller.File); file != "logtest_test.go" {
+					t.Fatalf("expected caller in logtest_test.go, got: %s", file)
+				}
+				if e.Stack == "" {
+					t.Fatalf("expected entry to have a stacktrace")
+				}
+			},
+		},
+		{
+			Name:  "Keeps Context Fields Apart",
+			Level: zap.DebugLevel,
+			SetupFunc: func(ctx context.Context) {
+				ctx = log.With(ctx, zap.String("request_id", "abc"))
+				log.Info(ctx, "my Info message", zap.Int("status", 200), zap.Error(errors.New("my error")))
+			},
+			AssertFunc: func(t *testing.T, logs *logtest.Logs) {
+				e := logs.FilterField(zap.String("request_id", "abc")).All()[0]
+
+				fields := e.FieldMap()
+				if fields["status"] != int64(200) || fields["error"] != "my error" {
+					t.Fatalf("unexpected fields: %v", fields)
+				}
+				if ctx := e.ContextMap(); len(ctx) != 1 || ctx["request_id"] != "abc" {
+					t.Fatalf("unexpected context fields: %v", ctx)
+				}
+				if f, ok := e.Field("request_id"); !ok || f.String != "abc" {
+					t.Fatalf("expected request_id field, got: %v", f)
+				}
+			},
+		},
+		{
+			Name:  "Filters Compose",
+			Level: zap.DebugLevel,
+			SetupFunc: func(ctx context.Context) {
+				log.Info(ctx, "request done", zap.Int("status", 200))
+				log.Info(ctx, "request done", zap.Int("status", 500))
+				log.Warn(ctx, "request done", zap.Int("status", 500))
+			},
+			AssertFunc: func(t *testing.T, logs *logtest.Logs) {
+				filtered := logs.FilterMessage("request done").FilterLevel(zap.InfoLevel).FilterField(zap.Int("status", 500))
+				if filtered.Len() != 1 {
+					t.Fatalf("expected 1 entry, got: %d", filtered.Len())
+				}
+				if n := len(logs.TakeAll()); n != 3 || logs.Len() != 0 {
+					t.Fatalf("expected to take 3 entries leaving none, took %d leaving %d", n, logs.Len())
+				}
+			},
+		},
+		{
+			Name:  "Child Level",
+			Level: zap.DebugLevel,
+			SetupFunc: func(ctx context.Context) {
+				ctx = log.WithLevel(ctx, zap.WarnLevel)
+				log.Info(ctx, "my Info message")
+				log.Warn(ctx, "my Warn message")
+			},
+			AssertFunc: func(t *testing.T, logs *logtest.Logs) {
+				if logs.Len() != 1 || logs.All()[0].Level != zap.WarnLevel {
+					t.Fatalf("expected only the warn entry, got: %v", logs.All())
+				}
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx, logs := logtest.NewContext(context.Background(), tc.Level)
+			tc.SetupFunc(ctx)
+			tc.AssertFunc(t, logs)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format:
+//
+//	# HELP log_entries_total Number of log entries encoded to be written.
+//	# TYPE log_entries_total counter
+//	log_entries_total{level="error",logger="http"} 12
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	bw := bufio.NewWriter(w)
+	series := m.Series()
+
+	families := []struct {
+		name, help string
+		value      func(Series) uint64
+	}{
+		{"log_entries_total", "Number of log entries encoded to be written.", func(s Series) uint64 { return s.Entries }},
+		{"log_bytes_total", "Number of bytes of the encoded log entries.", func(s Series) uint64 { return s.Bytes }},
+		{"log_discarded_entries_total", "Number of enabled log entries discarded, such as by sampling.", func(s Series) uint64 { return s.Discarded }},
+		{"log_dropped_entries_total", "Number of log entries that failed to be encoded or written.", func(s Series) uint64 { return s.Dropped }},
+	}
+
+	for _, f := range families {
+		writeHeader(bw, f.name, f.help)
+		for _, s := range series {
+			bw.WriteString(f.name)
+			bw.WriteString(`{level="`)
+			bw.WriteString(s.Level.String())
+			bw.WriteString(`",logger="`)
+			bw.WriteString(escapeLabel(s.Logger))
+			bw.WriteString(`"} `)
+			bw.WriteString(strconv.FormatUint(f.value(s), 10))
+			bw.WriteByte('\n')
+		}
+	}
+
+	writeHeader(bw, "log_truncated_entries_total", "Number of log entries with truncated values.")
+	bw.WriteString("log_truncated_entries_total ")
+	bw.WriteString(strconv.FormatUint(m.truncated.Load(), 10))
+	bw.WriteByte('\n')
+
+	bw.Flush()
+}
+
+func writeHeader(w *bufio.Writer, name, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " counter\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value as the text format requires.
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,196 @@
+// Package metrics counts the entries and bytes logged by level and logger
+// name, so that spikes of error logs can be alerted on. Counts are exposed
+// in the Prometheus text format through an http.Handler, and through a Go API
+// to feed other metrics libraries.
+//
+//	m := metrics.New()
+//	logger := log.NewProductionLogger(&lvl, log.WithMetrics(m))
+//	http.Handle("/metrics", m)
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/internal/checked"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Metrics holds the log counters. All methods are safe for concurrent use.
+type Metrics struct {
+	mu     sync.RWMutex
+	series map[seriesKey]*counters
+
+	truncated encoders.Counter
+}
+
+// New returns a set of metrics with all counters at zero.
+func New() *Metrics {
+	return &Metrics{
+		series: make(map[seriesKey]*counters),
+	}
+}
+
+// Series holds the counters of the entries of a level and logger name.
+type Series struct {
+	Level  zapcore.Level
+	Logger string
+
+	// Entries is the number of entries encoded to be written.
+	Entries uint64
+	// Bytes is the number of bytes of the encoded entries.
+	Bytes uint64
+	// Discarded is the number of enabled entries the core wrapped by
+	// WrapCore didn't write, such as the ones discarded by sampling.
+	Discarded uint64
+	// Dropped is the number of entries that failed to be encoded or written.
+	// Entries that failed to be written are counted as encoded too.
+	Dropped uint64
+}
+
+// Series returns the current counters of every level and logger name that
+// logged anything, sorted by logger name and level.
+func (m *Metrics) Series() []Series {
+	m.mu.RLock()
+	series := make([]Series, 0, len(m.series))
+	for k, c := range m.series {
+		series = append(series, Series{
+			Level:     k.level,
+			Logger:    k.logger,
+			Entries:   atomic.LoadUint64(&c.entries),
+			Bytes:     atomic.LoadUint64(&c.bytes),
+			Discarded: atomic.LoadUint64(&c.discarded),
+			Dropped:   atomic.LoadUint64(&c.dropped),
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Logger != series[j].Logger {
+			return series[i].Logger < series[j].Logger
+		}
+		return series[i].Level < series[j].Level
+	})
+	return series
+}
+
+// Truncated returns the counter of entries with truncated values, to be
+// given to encoders.WithTruncatedCounter. WithMetrics does so already.
+func (m *Metrics) Truncated() *encoders.Counter {
+	return &m.truncated
+}
+
+type seriesKey struct {
+	level  zapcore.Level
+	logger string
+}
+
+type counters struct {
+	entries   uint64
+	bytes     uint64
+	discarded uint64
+	dropped   uint64
+}
+
+// counters returns the counters of the given level and logger name, creating
+// them if needed.
+func (m *Metrics) counters(lvl zapcore.Level, logger string) *counters {
+	k := seriesKey{level: lvl, logger: logger}
+
+	m.mu.RLock()
+	c, ok := m.series[k]
+	m.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok = m.series[k]; !ok {
+		c = &counters{}
+		m.series[k] = c
+	}
+	return c
+}
+
+// WrapEncoder returns an encoder counting the entries and bytes encoded by
+// the given one.
+func (m *Metrics) WrapEncoder(enc zapcore.Encoder) zapcore.Encoder {
+	return &countingEncoder{Encoder: enc, m: m}
+}
+
+// countingEncoder counts the entries encoded by the wrapped encoder.
+type countingEncoder struct {
+	zapcore.Encoder
+
+	m *Metrics
+}
+
+func (e *countingEncoder) Clone() zapcore.Encoder {
+	return &countingEncoder{
+		Encoder: e.Encoder.Clone(),
+		m:       e.m,
+	}
+}
+
+func (e *countingEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return buf, err
+	}
+
+	c := e.m.counters(ent.Level, ent.LoggerName)
+	atomic.AddUint64(&c.entries, 1)
+	atomic.AddUint64(&c.bytes, uint64(buf.Len()))
+	return buf, nil
+}
+
+// WrapCore returns a core counting the enabled entries the given core
+// discards, such as the ones dropped by a sampler, and the entries it fails
+// to write. It should wrap the core closest to the output, so that entries
+// discarded by other wrapping cores aren't counted.
+func (m *Metrics) WrapCore(core zapcore.Core) zapcore.Core {
+	return &countingCore{Core: core, m: m}
+}
+
+// countingCore counts the entries the wrapped core discards or fails to
+// write.
+type countingCore struct {
+	zapcore.Core
+
+	m *Metrics
+}
+
+// Check adds countingCore to the checked entry, which is then checked by the
+// wrapped core as it's written, so that entries it discards can be counted as
+// well as the errors of the ones it writes.
+func (c *countingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *countingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		atomic.AddUint64(&c.m.counters(ent.Level, ent.LoggerName).discarded, 1)
+		return nil
+	}
+
+	err := checked.Write(inner, ent, fields)
+	if err != nil {
+		atomic.AddUint64(&c.m.counters(ent.Level, ent.LoggerName).dropped, 1)
+	}
+	return err
+}
+
+func (c *countingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &countingCore{
+		Core: c.Core.With(fields),
+		m:    c.m,
+	}
+}
@@ -0,0 +1,110 @@
+package metrics_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// failingWriter fails the writes of entries containing "fail".
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) {
+	if strings.Contains(string(b), "fail") {
+		return 0, errors.New("write failed")
+	}
+	return len(b), nil
+}
+
+func (failingWriter) Sync() error { return nil }
+
+func newLogger(m *metrics.Metrics) log.Logger {
+	encoder := encoders.NewKeyValueEncoder(log.NewProductionEncoderConfig(), encoders.WithLimits(encoders.Limits{
+		MaxValue:  8,
+		Truncated: m.Truncated(),
+	}))
+	core := zapcore.NewCore(m.WrapEncoder(encoder), failingWriter{}, zap.DebugLevel)
+	core = zapcore.NewSampler(core, time.Minute, 2, 1000)
+
+	lvl := zap.NewAtomicLevelAt(zap.InfoLevel)
+	return log.New(m.WrapCore(core), &lvl)
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	l := newLogger(m)
+
+	l.Debug("disabled")
+	l.Info("my Info message")
+	l.Named("http").With(zap.String("path", "/")).Error("my Error message")
+	for i := 0; i < 5; i++ {
+		l.Warn("repeated")
+	}
+	l.Warn("fail to write")
+	l.Info("truncated", zap.String("value", "a long value"))
+
+	expected := []metrics.Series{
+		{Level: zap.InfoLevel, Logger: "", Entries: 2},
+		{Level: zap.WarnLevel, Logger: "", Entries: 3, Discarded: 3, Dropped: 1},
+		{Level: zap.ErrorLevel, Logger: "http", Entries: 1},
+	}
+
+	series := m.Series()
+	if len(series) != len(expected) {
+		t.Fatalf("expected %d series, got: %+v", len(expected), series)
+	}
+	for i, s := range series {
+		if s.Bytes == 0 {
+			t.Fatalf("expected series %d to count bytes, got: %+v", i, s)
+		}
+		s.Bytes = 0
+		if s != expected[i] {
+			t.Fatalf("expected series %+v, got: %+v", expected[i], s)
+		}
+	}
+
+	if n := m.Truncated().Load(); n != 1 {
+		t.Fatalf("expected 1 truncated entry, got: %d", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := metrics.New()
+	l := newLogger(m)
+	l.Named(`quoted"name`).Info("my Info message")
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := ioutil.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, line := range []string{
+		"# TYPE log_entries_total counter",
+		`log_entries_total{level="info",logger="quoted\"name"} 1`,
+		`log_discarded_entries_total{level="info",logger="quoted\"name"} 0`,
+		`log_dropped_entries_total{level="info",logger="quoted\"name"} 0`,
+		"log_truncated_entries_total 0",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("expected output to contain %s, got:\n%s", line, out)
+		}
+	}
+
+	if !strings.Contains(out, `log_bytes_total{level="info",logger="quoted\"name"} 1`) {
+		t.Fatalf("expected output to contain the bytes of the entry, got:\n%s", out)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+}
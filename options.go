@@ -0,0 +1,180 @@
+package log
+
+import (
+	"time"
+
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/metrics"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures the loggers created by NewProductionLogger and New.
+type Option func(*config)
+
+type config struct {
+	clock          Clock
+	timeEncoding   TimeEncoding
+	location       *time.Location
+	encoderOptions []encoders.Option
+	metrics        *metrics.Metrics
+	wrappers       []func(zapcore.Core) zapcore.Core
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		location: time.UTC,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Clock tells the time of the entries being logged.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock makes the logger take the time of entries from the given clock,
+// usually a fake one so that tests get deterministic timestamps. By default
+// entries are timestamped with time.Now.
+func WithClock(c Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// TimeEncoding is the format in which entry timestamps are written.
+type TimeEncoding int8
+
+const (
+	// RFC3339MicroTime writes timestamps with microsecond precision, padded
+	// with zeroes to make them fixed width: 2019-04-08T20:21:32.375067Z.
+	RFC3339MicroTime TimeEncoding = iota
+	// RFC3339NanoTime writes timestamps with nanosecond precision, padded
+	// with zeroes to make them fixed width: 2019-04-08T20:21:32.375067000Z.
+	RFC3339NanoTime
+	// EpochMillisTime writes timestamps as milliseconds since the Unix epoch.
+	EpochMillisTime
+	// EpochNanosTime writes timestamps as nanoseconds since the Unix epoch.
+	EpochNanosTime
+)
+
+// WithTimeEncoding sets the format of entry timestamps, RFC3339MicroTime by
+// default. It only applies to NewProductionLogger, as New is given an
+// already built core.
+func WithTimeEncoding(e TimeEncoding) Option {
+	return func(cfg *config) {
+		cfg.timeEncoding = e
+	}
+}
+
+// WithTimeZone writes RFC3339 timestamps in the given location, such as
+// time.Local, instead of UTC. It only applies to NewProductionLogger.
+func WithTimeZone(loc *time.Location) Option {
+	return func(cfg *config) {
+		cfg.location = loc
+	}
+}
+
+// WithEncoderOptions configures the key value encoder with the given options.
+// It only applies to NewProductionLogger.
+func WithEncoderOptions(opts ...encoders.Option) Option {
+	return func(cfg *config) {
+		cfg.encoderOptions = append(cfg.encoderOptions, opts...)
+	}
+}
+
+// WithMetrics counts the entries and bytes written by level and logger name
+// into m, as well as the entries that failed to be written and the ones with
+// values truncated by encoders.WithLimits.
+//
+// It only applies to NewProductionLogger, cores given to New can be wrapped
+// through the metrics WrapEncoder and WrapCore methods instead, giving
+// m.Truncated() to encoders.WithTruncatedCounter. Entries discarded by the
+// wrapped core, such as by a sampler, are only counted by WrapCore.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(cfg *config) {
+		cfg.metrics = m
+	}
+}
+
+// WithWrapCore wraps the core of the logger, the one writing the entries the
+// logger level allows. It's the extension point for hooks, such as the ones
+// forwarding entries to other services, which usually tee the given core:
+//
+//	log.WithWrapCore(func(core zapcore.Core) zapcore.Core {
+//		return zapcore.NewTee(core, hookCore)
+//	})
+//
+// Wrappers are applied in the order they are given.
+func WithWrapCore(wrap func(zapcore.Core) zapcore.Core) Option {
+	return func(cfg *config) {
+		cfg.wrappers = append(cfg.wrappers, wrap)
+	}
+}
+
+// timeEncoder returns the zap time encoder for the configured format.
+func (cfg *config) timeEncoder() zapcore.TimeEncoder {
+	switch cfg.timeEncoding {
+	case RFC3339NanoTime:
+		return layoutTimeEncoder("2006-01-02T15:04:05.000000000Z07:00", cfg.location)
+	case EpochMillisTime:
+		return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt64(t.UnixNano() / int64(time.Millisecond))
+		}
+	case EpochNanosTime:
+		return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt64(t.UnixNano())
+		}
+	default:
+		if cfg.location == time.UTC {
+			return rfc3399NanoTimeEncoder
+		}
+		return layoutTimeEncoder(rfc3339Micro, cfg.location)
+	}
+}
+
+// layoutTimeEncoder returns a time encoder formatting timestamps with the
+// given layout in the given location.
+func layoutTimeEncoder(layout string, loc *time.Location) zapcore.TimeEncoder {
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.In(loc).Format(layout))
+	}
+}
+
+// clockCore wraps the logger core and replaces the time of entries with the
+// one told by its clock.
+//
+// It must remain on top of the other cores, so that entries buffered by a
+// FlightRecorder are timestamped too.
+type clockCore struct {
+	zapcore.Core
+
+	clock Clock
+}
+
+// Check sets the entry time before the wrapped core adds the entry.
+func (c *clockCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	e.Time = c.clock.Now()
+	return c.Core.Check(e, ce)
+}
+
+// With adds structured context to the wrapped core.
+func (c *clockCore) With(fields []zapcore.Field) zapcore.Core {
+	return &clockCore{
+		Core:  c.Core.With(fields),
+		clock: c.clock,
+	}
+}
+
+// underClock applies wrap to the core below the clockCore, if any.
+func underClock(core zapcore.Core, wrap func(zapcore.Core) zapcore.Core) zapcore.Core {
+	if clkCore, ok := core.(*clockCore); ok {
+		return &clockCore{
+			Core:  wrap(clkCore.Core),
+			clock: clkCore.clock,
+		}
+	}
+	return wrap(core)
+}
@@ -0,0 +1,96 @@
+package log_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/logtest"
+	"github.com/emiguens/zapfmt/metrics"
+	"github.com/kami-zh/go-capturer"
+	"go.uber.org/zap"
+)
+
+func TestTimeOptions(t *testing.T) {
+	now := time.Date(2019, time.April, 8, 20, 21, 32, 375067123, time.UTC)
+
+	tt := []struct {
+		Name     string
+		Options  []log.Option
+		Expected string
+	}{
+		{
+			Name:     "RFC3339 Micro By Default",
+			Expected: "[ts:2019-04-08T20:21:32.375067Z]",
+		},
+		{
+			Name:     "RFC3339 Nano",
+			Options:  []log.Option{log.WithTimeEncoding(log.RFC3339NanoTime)},
+			Expected: "[ts:2019-04-08T20:21:32.375067123Z]",
+		},
+		{
+			Name:     "Epoch Millis",
+			Options:  []log.Option{log.WithTimeEncoding(log.EpochMillisTime)},
+			Expected: "[ts:1554754892375]",
+		},
+		{
+			Name:     "Epoch Nanos",
+			Options:  []log.Option{log.WithTimeEncoding(log.EpochNanosTime)},
+			Expected: "[ts:1554754892375067123]",
+		},
+		{
+			Name:     "Time Zone",
+			Options:  []log.Option{log.WithTimeZone(time.FixedZone("UTC-3", -3*60*60))},
+			Expected: "[ts:2019-04-08T17:21:32.375067-03:00]",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			clock := logtest.NewClock(now)
+			lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+
+			out := capturer.CaptureStderr(func() {
+				l := log.NewProductionLogger(&lvl, append(tc.Options, log.WithClock(clock))...)
+				l.Info("my Info message")
+				clock.Add(time.Hour)
+				l.WithLevel(zap.InfoLevel).Info("my Info message")
+			})
+
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("expected 2 lines, got: %q", out)
+			}
+			if !strings.HasPrefix(lines[0], tc.Expected) {
+				t.Fatalf("expected line to start with %s, got: %s", tc.Expected, lines[0])
+			}
+			if lines[0] == lines[1] {
+				t.Fatalf("expected the clock to move, got the same line twice: %s", lines[1])
+			}
+		})
+	}
+}
+
+func TestMetricsOption(t *testing.T) {
+	m := metrics.New()
+	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+
+	capturer.CaptureStderr(func() {
+		l := log.NewProductionLogger(&lvl,
+			log.WithMetrics(m),
+			log.WithEncoderOptions(encoders.WithLimits(encoders.Limits{MaxValue: 4})),
+		)
+		l.Info("my Info message", zap.String("key", "abc"))
+		l.Info("my Info message", zap.String("key", "long value"))
+	})
+
+	series := m.Series()
+	if len(series) != 1 || series[0].Entries != 2 {
+		t.Fatalf("expected 2 info entries, got: %+v", series)
+	}
+	if n := m.Truncated().Load(); n != 1 {
+		t.Fatalf("expected 1 truncated entry, got: %d", n)
+	}
+}
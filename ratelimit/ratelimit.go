@@ -0,0 +1,296 @@
+// Package ratelimit limits identical log entries, so a retry loop logging the
+// same warning thousands of times a second doesn't drown everything else.
+//
+// Entries are identical when they have the same level, message, caller and
+// values for the configured fields. Only the first entries of each window are
+// written, and once the window ends a summary entry tells how many were
+// suppressed:
+//
+//	[ts:2019-04-08T20:21:33.375067Z][level:warn][caller:db/conn.go:42][msg:connection failed][suppressed:9873][window:1s]
+//
+// Unlike zap's sampler, which only looks at the level and message, entries
+// logged from different places or about different keys are limited apart.
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/emiguens/zapfmt/internal/checked"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultLimit is the number of identical entries written per window,
+	// unless Config.Limit says otherwise.
+	DefaultLimit = 10
+
+	// DefaultWindow is the duration of a window, unless Config.Window says
+	// otherwise.
+	DefaultWindow = time.Second
+)
+
+// Config configures a Limiter.
+type Config struct {
+	// Limit is the number of identical entries written per window.
+	Limit int
+
+	// Window is the duration in which at most Limit identical entries are
+	// written.
+	Window time.Duration
+
+	// Fields are the keys of the fields telling entries apart, either given
+	// at the log site or added through With. For example, with "host" the
+	// same message logged about two hosts is limited separately.
+	Fields []string
+}
+
+// Limiter limits identical entries. All methods are safe for concurrent use.
+type Limiter struct {
+	cfg    Config
+	fields map[string]bool
+
+	mu     sync.Mutex
+	states map[string]*state
+
+	suppressed uint64
+
+	done   chan struct{}
+	closed sync.Once
+}
+
+// state tracks the window of a set of identical entries.
+type state struct {
+	start      time.Time
+	count      int
+	suppressed int
+
+	// ent and core are used to write the summary: the last suppressed entry
+	// and the core it would have been written to.
+	ent  zapcore.Entry
+	core zapcore.Core
+}
+
+// New returns a limiter and starts its background goroutine, which writes
+// the summaries of the windows that ended. Close must be called to stop it.
+//
+// Windows are measured with the time of the entries, so they follow the
+// clock given to log.WithClock, while the background goroutine looks for
+// ended windows with the system clock.
+func New(cfg Config) *Limiter {
+	if cfg.Limit <= 0 {
+		cfg.Limit = DefaultLimit
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+
+	l := &Limiter{
+		cfg:    cfg,
+		fields: make(map[string]bool, len(cfg.Fields)),
+		states: make(map[string]*state),
+		done:   make(chan struct{}),
+	}
+	for _, k := range cfg.Fields {
+		l.fields[k] = true
+	}
+
+	go l.run()
+	return l
+}
+
+// Suppressed returns the total number of entries suppressed.
+func (l *Limiter) Suppressed() uint64 {
+	return atomic.LoadUint64(&l.suppressed)
+}
+
+// Close writes the summaries of all the windows with suppressed entries and
+// stops the background goroutine.
+func (l *Limiter) Close() error {
+	l.closed.Do(func() {
+		close(l.done)
+		l.sweep(time.Time{}, true)
+	})
+	return nil
+}
+
+func (l *Limiter) run() {
+	ticker := time.NewTicker(l.cfg.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			l.sweep(now, false)
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// sweep writes the summaries of the windows ended by now, or of all of them
+// if all is set, and forgets the states of the ended windows.
+func (l *Limiter) sweep(now time.Time, all bool) {
+	var summaries []*state
+
+	l.mu.Lock()
+	for k, s := range l.states {
+		if !all && now.Sub(s.start) < l.cfg.Window {
+			continue
+		}
+		if s.suppressed > 0 {
+			summary := *s
+			summaries = append(summaries, &summary)
+		}
+		delete(l.states, k)
+	}
+	l.mu.Unlock()
+
+	for _, s := range summaries {
+		l.writeSummary(s, !all)
+	}
+}
+
+// allow reports whether the entry with the given key may be written, and
+// returns the summary of the previous window of the key if it must be
+// written first.
+func (l *Limiter) allow(key string, ent zapcore.Entry, core zapcore.Core) (bool, *state) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, ok := l.states[key]
+	if !ok {
+		l.states[key] = &state{start: ent.Time, count: 1}
+		return true, nil
+	}
+
+	var summary *state
+	if ent.Time.Sub(s.start) >= l.cfg.Window {
+		if s.suppressed > 0 {
+			prev := *s
+			summary = &prev
+		}
+		*s = state{start: ent.Time}
+	}
+
+	if s.count < l.cfg.Limit {
+		s.count++
+		return true, summary
+	}
+
+	s.suppressed++
+	s.ent = ent
+	s.core = core
+	atomic.AddUint64(&l.suppressed, 1)
+	return false, summary
+}
+
+// writeSummary writes the number of entries suppressed in a window, as the
+// last suppressed entry. The summary has the time of the end of the window,
+// or the time of the last suppressed entry if the window didn't end. Fields
+// given at the log site aren't kept, as they're owned by the caller, but the
+// ones added through With are.
+func (l *Limiter) writeSummary(s *state, ended bool) {
+	ent := s.ent
+	if ended {
+		ent.Time = s.start.Add(l.cfg.Window)
+	}
+	ent.Stack = ""
+
+	checked.Write(s.core.Check(ent, nil), ent, []zapcore.Field{
+		zap.Int("suppressed", s.suppressed),
+		zap.Stringer("window", l.cfg.Window),
+	})
+}
+
+// WrapCore returns a core writing to the given one at most the configured
+// number of identical entries per window. It's meant to be given to
+// log.WithWrapCore, so that it wraps the core closest to the output: entries
+// are checked by the given core only once they are allowed.
+func (l *Limiter) WrapCore(core zapcore.Core) zapcore.Core {
+	return &limitedCore{Core: core, l: l}
+}
+
+// limitedCore drops the entries over the limit of its Limiter.
+type limitedCore struct {
+	zapcore.Core
+
+	l *Limiter
+
+	// context holds the values of the configured fields added through With.
+	context []string
+}
+
+func (c *limitedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &limitedCore{
+		Core:    c.Core.With(fields),
+		l:       c.l,
+		context: c.l.appendValues(c.context[:len(c.context):len(c.context)], fields),
+	}
+}
+
+// Check adds the core for every enabled entry, as entries can only be told
+// apart once their caller and fields are known when written.
+func (c *limitedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *limitedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	allowed, summary := c.l.allow(c.key(ent, fields), ent, c.Core)
+	if summary != nil {
+		c.l.writeSummary(summary, true)
+	}
+	if !allowed {
+		return nil
+	}
+	return checked.Write(c.Core.Check(ent, nil), ent, fields)
+}
+
+// Sync writes the summaries of all the windows with suppressed entries before
+// syncing the wrapped core.
+func (c *limitedCore) Sync() error {
+	c.l.sweep(time.Time{}, true)
+	return c.Core.Sync()
+}
+
+// key identifies the entries considered identical.
+func (c *limitedCore) key(ent zapcore.Entry, fields []zapcore.Field) string {
+	var sb strings.Builder
+	sb.WriteString(ent.Level.String())
+	sb.WriteByte(0)
+	sb.WriteString(ent.Message)
+	sb.WriteByte(0)
+	sb.WriteString(ent.Caller.String())
+	for _, v := range c.context {
+		sb.WriteByte(0)
+		sb.WriteString(v)
+	}
+	for _, v := range c.l.appendValues(nil, fields) {
+		sb.WriteByte(0)
+		sb.WriteString(v)
+	}
+	return sb.String()
+}
+
+// appendValues appends the key and value of the configured fields.
+func (l *Limiter) appendValues(values []string, fields []zapcore.Field) []string {
+	if len(l.fields) == 0 {
+		return values
+	}
+	for _, f := range fields {
+		if !l.fields[f.Key] {
+			continue
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		values = append(values, f.Key+"="+fmt.Sprint(enc.Fields[f.Key]))
+	}
+	return values
+}
@@ -0,0 +1,139 @@
+package ratelimit_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/logtest"
+	"github.com/emiguens/zapfmt/ratelimit"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestLimiter(t *testing.T) {
+	tt := []struct {
+		Name      string
+		Config    ratelimit.Config
+		SetupFunc func(l log.Logger, clock *logtest.Clock)
+		Expected  []string
+	}{
+		{
+			Name:   "Limits Identical Entries",
+			Config: ratelimit.Config{Limit: 2, Window: time.Second},
+			SetupFunc: func(l log.Logger, clock *logtest.Clock) {
+				for i := 0; i < 5; i++ {
+					l.Warn("connection failed")
+				}
+				clock.Add(time.Second)
+				l.Warn("connection failed")
+			},
+			Expected: []string{
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:connection failed]",
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:connection failed]",
+				"[ts:2019-04-08T20:21:33.000000Z][level:warn][msg:connection failed][suppressed:3][window:1s]",
+				"[ts:2019-04-08T20:21:33.000000Z][level:warn][msg:connection failed]",
+			},
+		},
+		{
+			Name:   "Tells Apart Level Message And Fields",
+			Config: ratelimit.Config{Limit: 1, Window: time.Second, Fields: []string{"host"}},
+			SetupFunc: func(l log.Logger, clock *logtest.Clock) {
+				l.Warn("connection failed", zap.String("host", "a"), zap.Int("attempt", 1))
+				l.Warn("connection failed", zap.String("host", "a"), zap.Int("attempt", 2))
+				l.Warn("connection failed", zap.String("host", "b"))
+				l.With(zap.String("host", "c")).Warn("connection failed")
+				l.Error("connection failed")
+				l.Warn("connection closed")
+			},
+			Expected: []string{
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:connection failed][host:a][attempt:1]",
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:connection failed][host:b]",
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:connection failed][host:c]",
+				"[ts:2019-04-08T20:21:32.000000Z][level:error][msg:connection failed]",
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:connection closed]",
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:connection failed][suppressed:1][window:1s]",
+			},
+		},
+		{
+			Name:   "Keeps Context Fields In Summary",
+			Config: ratelimit.Config{Limit: 1, Window: time.Minute},
+			SetupFunc: func(l log.Logger, clock *logtest.Clock) {
+				l = l.Named("db").With(zap.String("request_id", "abc"))
+				l.Warn("connection failed")
+				clock.Add(time.Second)
+				l.Warn("connection failed")
+			},
+			Expected: []string{
+				"[ts:2019-04-08T20:21:32.000000Z][level:warn][logger:db][msg:connection failed][request_id:abc]",
+				"[ts:2019-04-08T20:21:33.000000Z][level:warn][logger:db][msg:connection failed][request_id:abc][suppressed:1][window:1m0s]",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf zaptest.Buffer
+			cfg := log.NewProductionEncoderConfig()
+			cfg.CallerKey = ""
+			core := zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &buf, zap.DebugLevel)
+
+			limiter := ratelimit.New(tc.Config)
+			clock := logtest.NewClock(time.Date(2019, time.April, 8, 20, 21, 32, 0, time.UTC))
+			lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+			l := log.New(core, &lvl, log.WithClock(clock), log.WithWrapCore(limiter.WrapCore))
+
+			tc.SetupFunc(l, clock)
+			limiter.Close()
+
+			lines := buf.Lines()
+			for i := range lines {
+				lines[i] = stacktraceless(lines[i])
+			}
+			if strings.Join(lines, "\n") != strings.Join(tc.Expected, "\n") {
+				t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(tc.Expected, "\n"), strings.Join(lines, "\n"))
+			}
+		})
+	}
+}
+
+func TestLimiterChecksWrappedCore(t *testing.T) {
+	var debugBuf, errorBuf zaptest.Buffer
+	cfg := log.NewProductionEncoderConfig()
+	cfg.CallerKey, cfg.StacktraceKey = "", ""
+	core := zapcore.NewTee(
+		zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &debugBuf, zap.DebugLevel),
+		zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &errorBuf, zap.ErrorLevel),
+	)
+
+	limiter := ratelimit.New(ratelimit.Config{Limit: 1, Window: time.Minute})
+	clock := logtest.NewClock(time.Date(2019, time.April, 8, 20, 21, 32, 0, time.UTC))
+	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+	l := log.New(core, &lvl, log.WithClock(clock), log.WithWrapCore(limiter.WrapCore))
+
+	l.Warn("connection failed")
+	l.Warn("connection failed")
+	l.Error("query failed")
+	limiter.Close()
+
+	expected := []string{
+		"[ts:2019-04-08T20:21:32.000000Z][level:error][msg:query failed]",
+	}
+	if lines := errorBuf.Lines(); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+	if n := len(debugBuf.Lines()); n != 3 {
+		t.Fatalf("expected 3 lines in the debug core, got %d", n)
+	}
+}
+
+// stacktraceless removes the stacktrace of error entries.
+func stacktraceless(line string) string {
+	if i := strings.Index(line, "[stacktrace:"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
@@ -0,0 +1,190 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// initialisms are written in upper case in Go names, as golint wants.
+var initialisms = map[string]bool{
+	"acl": true, "api": true, "ascii": true, "cpu": true, "css": true, "dns": true,
+	"eof": true, "guid": true, "html": true, "http": true, "https": true, "id": true,
+	"ip": true, "json": true, "qps": true, "ram": true, "rpc": true, "sla": true,
+	"smtp": true, "sql": true, "ssh": true, "tcp": true, "tls": true, "ttl": true,
+	"udp": true, "ui": true, "uid": true, "uuid": true, "uri": true, "url": true,
+	"utf8": true, "vm": true, "xml": true, "xmpp": true, "xsrf": true, "xss": true,
+}
+
+// Name returns the Go name of a key, in camel case with initialisms in upper
+// case: RequestID for request_id, and HTTPStatus for http.status.
+func Name(key string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if initialisms[strings.ToLower(part)] {
+			sb.WriteString(strings.ToUpper(part))
+			continue
+		}
+		sb.WriteString(strings.ToUpper(part[:1]))
+		sb.WriteString(part[1:])
+	}
+	return sb.String()
+}
+
+// typeConsts are the names of the constants of each type.
+var typeConsts = map[Type]string{
+	String: "String", Int: "Int", Uint: "Uint", Float: "Float", Bool: "Bool",
+	Duration: "Duration", Time: "Time", Strings: "Strings", Error: "Error", Any: "Any",
+}
+
+// goTypes are the Go types taken by the constructors of each type, and the
+// zap constructors they call.
+var goTypes = map[Type][2]string{
+	String:   {"string", "zap.String"},
+	Int:      {"int", "zap.Int"},
+	Uint:     {"uint", "zap.Uint"},
+	Float:    {"float64", "zap.Float64"},
+	Bool:     {"bool", "zap.Bool"},
+	Duration: {"time.Duration", "zap.Duration"},
+	Time:     {"time.Time", "zap.Time"},
+	Strings:  {"[]string", "zap.Strings"},
+	Error:    {"error", "zap.NamedError"},
+	Any:      {"interface{}", "zap.Any"},
+}
+
+// Generate returns the Go source of a package holding the typed constructors
+// of the keys of a schema, such as
+//
+//	// RequestID returns the request_id field, the ID of a request.
+//	func RequestID(v string) zap.Field {
+//		return zap.String(RequestIDKey, v)
+//	}
+//
+// along with constants for the keys, and the schema itself as the Schema
+// variable. source names the file the schema was read from, for the header
+// telling the code is generated.
+func Generate(s *Schema, pkg, source string) ([]byte, error) {
+	data := struct {
+		Package, Source string
+		Time            bool
+		Fields          []genField
+	}{Package: pkg, Source: source}
+
+	names := map[string]bool{"Schema": true}
+	for _, f := range s.fields {
+		names[f.Name] = true
+	}
+	for _, f := range s.fields {
+		if f.Name == "Schema" || names[f.Name+"Key"] {
+			return nil, fmt.Errorf("key %q has name %s, whose identifiers are taken", f.Key, f.Name)
+		}
+		if f.Type == Duration || f.Type == Time {
+			data.Time = true
+		}
+		data.Fields = append(data.Fields, genField{
+			Field:   f,
+			Doc:     comment(doc(f)),
+			Const:   typeConsts[f.Type],
+			GoType:  goTypes[f.Type][0],
+			Factory: goTypes[f.Type][1],
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := generated.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+type genField struct {
+	Field
+	Doc     string
+	Const   string
+	GoType  string
+	Factory string
+}
+
+// doc returns the documentation of the constructor of a key.
+func doc(f Field) string {
+	if f.Doc == "" {
+		return fmt.Sprintf("%s returns the %s field.", f.Name, f.Key)
+	}
+	d := strings.TrimSpace(f.Doc)
+	if !strings.HasSuffix(d, ".") {
+		d += "."
+	}
+	return fmt.Sprintf("%s returns the %s field, %s", f.Name, f.Key, d)
+}
+
+// comment returns text as a Go comment wrapped at 80 columns.
+func comment(text string) string {
+	var sb strings.Builder
+	line := "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > 77 && line != "//" {
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+			line = "//"
+		}
+		line += " " + word
+	}
+	sb.WriteString(line)
+	return sb.String()
+}
+
+var generated = template.Must(template.New("").Funcs(template.FuncMap{
+	"quote":  strconv.Quote,
+	"quotes": quotes,
+}).Parse(`// Code generated by fieldgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .Time}}
+	"time"
+{{end}}
+	"github.com/emiguens/zapfmt/schema"
+	"go.uber.org/zap"
+)
+
+// Keys of the schema.
+const (
+{{- range .Fields}}
+	{{.Name}}Key = {{quote .Key}}
+{{- end}}
+)
+{{range .Fields}}
+{{.Doc}}
+func {{.Name}}(v {{.GoType}}) zap.Field {
+	return {{.Factory}}({{.Name}}Key, v)
+}
+{{end}}
+// Schema is the schema the constructors were generated from, for
+// schema.NewValidator.
+var Schema = schema.MustNew([]schema.Field{
+{{- range .Fields}}
+	{Key: {{quote .Key}}, Type: schema.{{.Const}}, Name: {{quote .Name}}
+		{{- if .Aliases}}, Aliases: []string{ {{- quotes .Aliases -}} }{{end}}
+		{{- if .Field.Doc}}, Doc: {{quote .Field.Doc}}{{end}}},
+{{- end}}
+})
+`))
+
+func quotes(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,48 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/emiguens/zapfmt/logtest"
+	"github.com/emiguens/zapfmt/schema"
+)
+
+func TestName(t *testing.T) {
+	tt := []struct {
+		Key      string
+		Expected string
+	}{
+		{"request_id", "RequestID"},
+		{"http.status", "HTTPStatus"},
+		{"remote_addr", "RemoteAddr"},
+		{"user_uuid", "UserUUID"},
+		{"attempt", "Attempt"},
+		{"p99_latency", "P99Latency"},
+	}
+
+	for _, tc := range tt {
+		if got := schema.Name(tc.Key); got != tc.Expected {
+			t.Errorf("expected %s for %s, got %s", tc.Expected, tc.Key, got)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s := parse(t, testSchema)
+	src, err := schema.Generate(s, "keys", "schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logtest.AssertGolden(t, t.Name(), src)
+}
+
+func TestGenerateTakenNames(t *testing.T) {
+	for _, fields := range [][]schema.Field{
+		{{Key: "schema", Type: schema.String}},
+		{{Key: "user", Type: schema.String}, {Key: "user_key", Type: schema.String}},
+	} {
+		if _, err := schema.Generate(schema.MustNew(fields), "keys", "schema.json"); err == nil {
+			t.Errorf("expected an error generating %+v", fields)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Package schema declares the well-known keys of logs and the types of their
+// values, so that the same thing is logged under the same key everywhere:
+// request_id, rather than requestId in one service and reqID in another.
+//
+// Schemas are written in JSON, usually next to the package of constructors
+// generated from them by the fieldgen command:
+//
+//	{
+//		"fields": [
+//			{"key": "request_id", "type": "string", "aliases": ["requestId", "reqID"], "doc": "the ID of a request across services."},
+//			{"key": "http.status", "type": "int"}
+//		]
+//	}
+//
+// Keys are snake_case, dotted to name the keys in a zap.Namespace. Aliases
+// are keys seen for the same thing, which a Validator reports.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/emiguens/zapfmt/internal/lazy"
+	"go.uber.org/zap/zapcore"
+)
+
+// Type is the type of the values of a key.
+type Type string
+
+// Types of values, and the zap fields they're logged with.
+const (
+	String   Type = "string"   // zap.String
+	Int      Type = "int"      // zap.Int, and any signed integer
+	Uint     Type = "uint"     // zap.Uint, and any unsigned integer
+	Float    Type = "float"    // zap.Float64 or zap.Float32
+	Bool     Type = "bool"     // zap.Bool
+	Duration Type = "duration" // zap.Duration
+	Time     Type = "time"     // zap.Time
+	Strings  Type = "strings"  // zap.Strings, and any array
+	Error    Type = "error"    // zap.NamedError
+	Any      Type = "any"      // any field
+)
+
+var types = []Type{String, Int, Uint, Float, Bool, Duration, Time, Strings, Error, Any}
+
+// Matches reports whether fields of type t hold values of this type.
+func (typ Type) Matches(t zapcore.FieldType) bool {
+	switch typ {
+	case String:
+		return t == zapcore.StringType
+	case Int:
+		return t == zapcore.Int64Type || t == zapcore.Int32Type || t == zapcore.Int16Type || t == zapcore.Int8Type
+	case Uint:
+		return t == zapcore.Uint64Type || t == zapcore.Uint32Type || t == zapcore.Uint16Type || t == zapcore.Uint8Type || t == zapcore.UintptrType
+	case Float:
+		return t == zapcore.Float64Type || t == zapcore.Float32Type
+	case Bool:
+		return t == zapcore.BoolType
+	case Duration:
+		return t == zapcore.DurationType
+	case Time:
+		return t == zapcore.TimeType
+	case Strings:
+		return t == zapcore.ArrayMarshalerType
+	case Error:
+		return t == zapcore.ErrorType
+	case Any:
+		return true
+	}
+	return false
+}
+
+// typeName describes the values of fields of type t.
+func typeName(t zapcore.FieldType) string {
+	switch t {
+	case zapcore.StringType:
+		return "string"
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return "int"
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+		return "uint"
+	case zapcore.Float64Type, zapcore.Float32Type:
+		return "float"
+	case zapcore.BoolType:
+		return "bool"
+	case zapcore.DurationType:
+		return "duration"
+	case zapcore.TimeType:
+		return "time"
+	case zapcore.ErrorType:
+		return "error"
+	case zapcore.ArrayMarshalerType:
+		return "array"
+	case zapcore.ObjectMarshalerType:
+		return "object"
+	case zapcore.StringerType:
+		return "stringer"
+	case zapcore.BinaryType, zapcore.ByteStringType:
+		return "bytes"
+	case zapcore.Complex128Type, zapcore.Complex64Type:
+		return "complex"
+	case zapcore.NamespaceType:
+		return "namespace"
+	}
+	return "reflected value"
+}
+
+// Field declares a key.
+type Field struct {
+	Key  string `json:"key"`
+	Type Type   `json:"type"`
+
+	// Name is the name of the constructor generated for the key, derived
+	// from it if empty: RequestID for request_id.
+	Name string `json:"name,omitempty"`
+
+	// Aliases are other keys seen for the same thing, such as requestId.
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Doc describes the values of the key as a noun phrase, such as "the ID
+	// of a request across services.", for the documentation of its
+	// constructor.
+	Doc string `json:"doc,omitempty"`
+}
+
+// Schema is a set of keys. It's safe for concurrent use.
+type Schema struct {
+	fields  []Field
+	keys    map[string]int
+	aliases map[string]int
+
+	// namespaces holds the prefixes of dotted keys: http for http.status.
+	namespaces map[string]bool
+}
+
+var (
+	keyPattern  = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*(\.[a-z][a-z0-9]*(_[a-z0-9]+)*)*$`)
+	namePattern = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+)
+
+// New returns a schema declaring the given keys, or an error if a key isn't
+// snake_case, is declared twice, or has an unknown type.
+func New(fields []Field) (*Schema, error) {
+	s := &Schema{
+		fields:     make([]Field, len(fields)),
+		keys:       make(map[string]int, len(fields)),
+		aliases:    make(map[string]int),
+		namespaces: make(map[string]bool),
+	}
+	names := make(map[string]string, len(fields))
+
+	for i, f := range fields {
+		if !keyPattern.MatchString(f.Key) {
+			return nil, fmt.Errorf("key %q is not snake_case", f.Key)
+		}
+		if _, ok := s.keys[f.Key]; ok {
+			return nil, fmt.Errorf("key %q is declared twice", f.Key)
+		}
+		if !knownType(f.Type) {
+			return nil, fmt.Errorf("key %q has unknown type %q", f.Key, f.Type)
+		}
+
+		if f.Name == "" {
+			f.Name = Name(f.Key)
+		}
+		if !namePattern.MatchString(f.Name) {
+			return nil, fmt.Errorf("key %q has invalid name %q, must be an exported Go identifier", f.Key, f.Name)
+		}
+		if other, ok := names[f.Name]; ok {
+			return nil, fmt.Errorf("keys %q and %q have the same name %s", other, f.Key, f.Name)
+		}
+		names[f.Name] = f.Key
+
+		for _, a := range f.Aliases {
+			if _, ok := s.keys[a]; ok || a == f.Key {
+				return nil, fmt.Errorf("alias %q of key %q is declared as a key", a, f.Key)
+			}
+			if j, ok := s.aliases[a]; ok {
+				return nil, fmt.Errorf("alias %q is declared for keys %q and %q", a, fields[j].Key, f.Key)
+			}
+			s.aliases[a] = i
+		}
+
+		f.Aliases = append([]string(nil), f.Aliases...)
+		s.fields[i] = f
+		s.keys[f.Key] = i
+		for j := strings.LastIndexByte(f.Key, '.'); j > 0; j = strings.LastIndexByte(f.Key[:j], '.') {
+			s.namespaces[f.Key[:j]] = true
+		}
+	}
+
+	for a := range s.aliases {
+		if _, ok := s.keys[a]; ok {
+			return nil, fmt.Errorf("key %q is declared as an alias too", a)
+		}
+	}
+	for ns := range s.namespaces {
+		if _, ok := s.keys[ns]; ok {
+			return nil, fmt.Errorf("key %q is declared as a namespace too", ns)
+		}
+	}
+	return s, nil
+}
+
+// MustNew is like New but panics on errors. It's meant for the schemas of
+// generated code, which were checked when generated.
+func MustNew(fields []Field) *Schema {
+	s, err := New(fields)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Parse reads a schema written in JSON.
+func Parse(r io.Reader) (*Schema, error) {
+	var doc struct {
+		Fields []Field `json:"fields"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	s, err := New(doc.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return s, nil
+}
+
+func knownType(t Type) bool {
+	for _, typ := range types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields returns the keys of the schema, in the order they were declared.
+func (s *Schema) Fields() []Field {
+	return append([]Field(nil), s.fields...)
+}
+
+// Lookup returns the declaration of a key, or of the key it's an alias of.
+func (s *Schema) Lookup(key string) (Field, bool) {
+	if i, ok := s.keys[key]; ok {
+		return s.fields[i], true
+	}
+	if i, ok := s.aliases[key]; ok {
+		return s.fields[i], true
+	}
+	return Field{}, false
+}
+
+// Check returns an error if a field doesn't follow the schema: if its key is
+// an alias, or its value is not of the declared type. Keys that aren't in the
+// schema are only reported when strict is set. The values of lazy fields
+// aren't known yet, so only their keys are checked.
+func (s *Schema) Check(f zapcore.Field, strict bool) error {
+	return s.check(f.Key, f, strict)
+}
+
+// check checks the field logged with key, which is dotted if the field is in
+// a namespace.
+func (s *Schema) check(key string, f zapcore.Field, strict bool) error {
+	t := f.Type
+	isLazy := lazy.IsLazy(f)
+	if t == zapcore.SkipType && !isLazy {
+		return nil
+	}
+	if i, ok := s.aliases[key]; ok {
+		return fmt.Errorf("key %q is an alias of %q", key, s.fields[i].Key)
+	}
+
+	i, ok := s.keys[key]
+	if !ok {
+		if t == zapcore.NamespaceType && s.namespaces[key] {
+			return nil
+		}
+		if strict {
+			return fmt.Errorf("key %q is not in the schema%s", key, s.suggest(key))
+		}
+		return nil
+	}
+	if isLazy {
+		return nil
+	}
+	if decl := s.fields[i]; t == zapcore.NamespaceType || !decl.Type.Matches(t) {
+		return fmt.Errorf("key %q must be of type %s, got %s", key, decl.Type, typeName(t))
+	}
+	return nil
+}
+
+// suggest returns a hint of the key that may have been meant instead of an
+// unknown one: one written the same but for case and separators.
+func (s *Schema) suggest(key string) string {
+	for _, f := range s.fields {
+		if normalize(f.Key) == normalize(key) {
+			return fmt.Sprintf(", did you mean %q?", f.Key)
+		}
+	}
+	return ""
+}
+
+func normalize(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
@@ -0,0 +1,169 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emiguens/zapfmt/fields"
+	"github.com/emiguens/zapfmt/schema"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const testSchema = `{
+	"fields": [
+		{"key": "request_id", "type": "string", "aliases": ["requestId", "reqID"], "doc": "the ID of a request."},
+		{"key": "http.status", "type": "int", "aliases": ["status_code"]},
+		{"key": "duration", "type": "duration"},
+		{"key": "tags", "type": "strings"},
+		{"key": "payload", "type": "any"}
+	]
+}`
+
+func parse(t *testing.T, s string) *schema.Schema {
+	t.Helper()
+	sch, err := schema.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sch
+}
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Schema   string
+		Expected string
+	}{
+		{
+			Name:     "Invalid JSON",
+			Schema:   `{"fields": [`,
+			Expected: "invalid schema: unexpected EOF",
+		},
+		{
+			Name:     "Unknown Attribute",
+			Schema:   `{"fields": [{"key": "a", "type": "string", "kind": "id"}]}`,
+			Expected: `invalid schema: json: unknown field "kind"`,
+		},
+		{
+			Name:     "Key Not Snake Case",
+			Schema:   `{"fields": [{"key": "requestId", "type": "string"}]}`,
+			Expected: `invalid schema: key "requestId" is not snake_case`,
+		},
+		{
+			Name:     "Key Declared Twice",
+			Schema:   `{"fields": [{"key": "a", "type": "string"}, {"key": "a", "type": "int"}]}`,
+			Expected: `invalid schema: key "a" is declared twice`,
+		},
+		{
+			Name:     "Unknown Type",
+			Schema:   `{"fields": [{"key": "a", "type": "text"}]}`,
+			Expected: `invalid schema: key "a" has unknown type "text"`,
+		},
+		{
+			Name:     "Invalid Name",
+			Schema:   `{"fields": [{"key": "a", "type": "string", "name": "a"}]}`,
+			Expected: `invalid schema: key "a" has invalid name "a", must be an exported Go identifier`,
+		},
+		{
+			Name:     "Same Name",
+			Schema:   `{"fields": [{"key": "http_status", "type": "int"}, {"key": "http.status", "type": "int"}]}`,
+			Expected: `invalid schema: keys "http_status" and "http.status" have the same name HTTPStatus`,
+		},
+		{
+			Name:     "Alias Declared As Key",
+			Schema:   `{"fields": [{"key": "a", "type": "string", "aliases": ["b"]}, {"key": "b", "type": "int"}]}`,
+			Expected: `invalid schema: key "b" is declared as an alias too`,
+		},
+		{
+			Name:     "Alias Declared Twice",
+			Schema:   `{"fields": [{"key": "a", "type": "string", "aliases": ["c"]}, {"key": "b", "type": "int", "aliases": ["c"]}]}`,
+			Expected: `invalid schema: alias "c" is declared for keys "a" and "b"`,
+		},
+		{
+			Name:     "Namespace Declared As Key",
+			Schema:   `{"fields": [{"key": "http", "type": "string"}, {"key": "http.status", "type": "int"}]}`,
+			Expected: `invalid schema: key "http" is declared as a namespace too`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := schema.Parse(strings.NewReader(tc.Schema))
+			if err == nil || err.Error() != tc.Expected {
+				t.Fatalf("expected error %q, got %v", tc.Expected, err)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	s := parse(t, testSchema)
+
+	for _, key := range []string{"request_id", "reqID"} {
+		f, ok := s.Lookup(key)
+		if !ok || f.Key != "request_id" || f.Name != "RequestID" || f.Type != schema.String {
+			t.Errorf("unexpected declaration of %s: %+v, %v", key, f, ok)
+		}
+	}
+	if _, ok := s.Lookup("request"); ok {
+		t.Error("expected request not to be declared")
+	}
+	if n := len(s.Fields()); n != 5 {
+		t.Errorf("expected 5 fields, got %d", n)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := parse(t, testSchema)
+
+	tt := []struct {
+		Name     string
+		Field    zapcore.Field
+		Strict   bool
+		Expected string
+	}{
+		{Name: "Declared", Field: zap.String("request_id", "abc")},
+		{Name: "Alias", Field: zap.String("requestId", "abc"), Expected: `key "requestId" is an alias of "request_id"`},
+		{Name: "Wrong Type", Field: zap.Int("request_id", 1), Expected: `key "request_id" must be of type string, got int`},
+		{Name: "Reflected", Field: zap.Reflect("duration", 1), Expected: `key "duration" must be of type duration, got reflected value`},
+		{Name: "Any Resolved", Field: zap.Any("duration", time.Second)},
+		{Name: "Smaller Int", Field: zap.Int8("http.status", 1)},
+		{Name: "Array", Field: zap.Strings("tags", []string{"a"})},
+		{Name: "Any Type", Field: zap.Binary("payload", nil)},
+		{Name: "Skipped", Field: zap.NamedError("request_id", nil)},
+		{Name: "Lazy", Field: fields.Lazy("request_id", func() zap.Field { return zap.String("", "abc") })},
+		{Name: "Lazy Alias", Field: fields.Lazy("requestId", func() zap.Field { return zap.String("", "abc") }), Expected: `key "requestId" is an alias of "request_id"`},
+		{Name: "Lazy Not Declared Strict", Field: fields.Lazy("size", func() zap.Field { return zap.Int("", 1) }), Strict: true, Expected: `key "size" is not in the schema`},
+		{Name: "Not Declared", Field: zap.Int("size", 1)},
+		{Name: "Not Declared Strict", Field: zap.Int("size", 1), Strict: true, Expected: `key "size" is not in the schema`},
+		{Name: "Suggestion", Field: zap.String("Request-ID", "abc"), Strict: true, Expected: `key "Request-ID" is not in the schema, did you mean "request_id"?`},
+		{Name: "Namespace", Field: zap.Namespace("http"), Strict: true},
+		{Name: "Namespace Declared As Key", Field: zap.Namespace("duration"), Expected: `key "duration" must be of type duration, got namespace`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := s.Check(tc.Field, tc.Strict)
+			if tc.Expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.Expected {
+				t.Fatalf("expected error %q, got %v", tc.Expected, err)
+			}
+		})
+	}
+}
+
+func TestMustNew(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	schema.MustNew([]schema.Field{{Key: "a"}})
+}
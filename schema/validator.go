@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/emiguens/zapfmt/internal/checked"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ViolationMessage is the message of the entries written for fields that
+// don't follow the schema.
+const ViolationMessage = "field schema violation"
+
+// ValidatorConfig configures a Validator.
+type ValidatorConfig struct {
+	// Strict reports keys that are not in the schema too, besides aliases and
+	// values of the wrong type.
+	Strict bool
+
+	// Panic makes violations panic once they're written, so that they fail
+	// tests instead of going unnoticed.
+	Panic bool
+
+	// Clock tells the time of the violations found in fields added through
+	// With, which aren't written along an entry. It should be the clock given
+	// to log.WithClock, if any. Violations are timestamped with time.Now if
+	// nil.
+	Clock interface {
+		Now() time.Time
+	}
+}
+
+// Validator checks the fields of entries against a schema. It's meant for
+// development, as every field is checked as it's logged:
+//
+//	if dev {
+//		v := schema.NewValidator(fields.Schema, schema.ValidatorConfig{Strict: true})
+//		opts = append(opts, log.WithWrapCore(v.WrapCore))
+//	}
+//
+// Violations are written as warnings after the entry with the offending
+// field, with the caller of the entry:
+//
+//	[ts:2019-04-08T20:21:32.375067Z][level:warn][caller:api/handler.go:42][msg:field schema violation][violation:key \"requestId\" is an alias of \"request_id\"][entry:request handled]
+//
+// Fields added through With are checked when added, and their violations
+// written right away, without a caller.
+type Validator struct {
+	schema *Schema
+	cfg    ValidatorConfig
+
+	violations uint64
+}
+
+// NewValidator returns a validator checking fields against the given schema.
+func NewValidator(s *Schema, cfg ValidatorConfig) *Validator {
+	return &Validator{schema: s, cfg: cfg}
+}
+
+// Violations returns the number of violations found.
+func (v *Validator) Violations() uint64 {
+	return atomic.LoadUint64(&v.violations)
+}
+
+// WrapCore returns a core checking the fields written to the given one. It's
+// meant to be given to log.WithWrapCore.
+func (v *Validator) WrapCore(core zapcore.Core) zapcore.Core {
+	return &validatingCore{Core: core, v: v}
+}
+
+// validatingCore reports the fields that don't follow the schema of its
+// Validator.
+type validatingCore struct {
+	zapcore.Core
+
+	v *Validator
+
+	// namespace is the dotted path of the namespace opened through With, if
+	// any, where the keys of later fields are, and outer the core before it
+	// was opened, which violations are written to.
+	namespace string
+	outer     zapcore.Core
+}
+
+func (c *validatingCore) With(fields []zapcore.Field) zapcore.Core {
+	child := &validatingCore{Core: c.Core.With(fields), v: c.v, outer: c.outer}
+	var errs []error
+	child.namespace, errs = c.v.check(c.namespace, fields)
+	if c.namespace == "" && child.namespace != "" {
+		for i, f := range fields {
+			if f.Type == zapcore.NamespaceType {
+				child.outer = c.Core.With(fields[:i])
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		c.v.report(c.reporter(), zapcore.Entry{Time: c.v.now()}, errs)
+	}
+	return child
+}
+
+// now returns the time of the violations that aren't written along an entry.
+func (v *Validator) now() time.Time {
+	if v.cfg.Clock != nil {
+		return v.cfg.Clock.Now()
+	}
+	return time.Now()
+}
+
+// reporter returns the core violations are written to.
+func (c *validatingCore) reporter() zapcore.Core {
+	if c.outer != nil {
+		return c.outer
+	}
+	return c.Core
+}
+
+// Check adds validatingCore to the checked entry, as fields are only known
+// once the entry is written, when the wrapped core checks it in turn.
+func (c *validatingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *validatingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := checked.Write(c.Core.Check(ent, nil), ent, fields)
+	if _, errs := c.v.check(c.namespace, fields); len(errs) > 0 {
+		c.v.report(c.reporter(), ent, errs)
+	}
+	return err
+}
+
+// check checks fields whose keys are in the given namespace, returning the
+// namespace of the fields after them.
+func (v *Validator) check(namespace string, fields []zapcore.Field) (string, []error) {
+	var errs []error
+	for _, f := range fields {
+		key := f.Key
+		if namespace != "" {
+			key = namespace + "." + key
+		}
+		if err := v.schema.check(key, f, v.cfg.Strict); err != nil {
+			errs = append(errs, err)
+		}
+		if f.Type == zapcore.NamespaceType {
+			namespace = key
+		}
+	}
+	return namespace, errs
+}
+
+// report writes the violations found in the fields of an entry as warnings
+// with its time, logger name and caller, then panics if configured to.
+func (v *Validator) report(core zapcore.Core, ent zapcore.Entry, errs []error) {
+	atomic.AddUint64(&v.violations, uint64(len(errs)))
+
+	warning := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       ent.Time,
+		LoggerName: ent.LoggerName,
+		Caller:     ent.Caller,
+		Message:    ViolationMessage,
+	}
+	for _, err := range errs {
+		fields := []zapcore.Field{zap.String("violation", err.Error())}
+		if ent.Message != "" {
+			fields = append(fields, zap.String("entry", ent.Message))
+		}
+		checked.Write(core.Check(warning, nil), warning, fields)
+	}
+
+	if v.cfg.Panic {
+		texts := make([]string, len(errs))
+		for i, err := range errs {
+			texts[i] = err.Error()
+		}
+		panic(fmt.Sprintf("%s: %s", ViolationMessage, strings.Join(texts, "; ")))
+	}
+}
@@ -0,0 +1,163 @@
+package schema_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/encoders"
+	"github.com/emiguens/zapfmt/fields"
+	"github.com/emiguens/zapfmt/logtest"
+	"github.com/emiguens/zapfmt/schema"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestValidator(t *testing.T) {
+	tt := []struct {
+		Name      string
+		Config    schema.ValidatorConfig
+		SetupFunc func(l log.Logger)
+		Expected  []string
+	}{
+		{
+			Name: "Reports Aliases And Types",
+			SetupFunc: func(l log.Logger) {
+				l.Info("request handled", zap.String("request_id", "abc"), zap.Int("size", 1))
+				l.Info("request handled", zap.String("reqID", "abc"), zap.String("http.status", "200"))
+			},
+			Expected: []string{
+				`[level:info][msg:request handled][request_id:abc][size:1]`,
+				`[level:info][msg:request handled][reqID:abc][http.status:200]`,
+				`[level:warn][msg:field schema violation][violation:key \"reqID\" is an alias of \"request_id\"][entry:request handled]`,
+				`[level:warn][msg:field schema violation][violation:key \"http.status\" must be of type int, got string][entry:request handled]`,
+			},
+		},
+		{
+			Name:   "Strict",
+			Config: schema.ValidatorConfig{Strict: true},
+			SetupFunc: func(l log.Logger) {
+				l.Named("api").Info("request handled", zap.Int("size", 1))
+			},
+			Expected: []string{
+				`[level:info][logger:api][msg:request handled][size:1]`,
+				`[level:warn][logger:api][msg:field schema violation][violation:key \"size\" is not in the schema][entry:request handled]`,
+			},
+		},
+		{
+			Name:   "Namespaces",
+			Config: schema.ValidatorConfig{Strict: true},
+			SetupFunc: func(l log.Logger) {
+				l.Info("request handled", zap.Namespace("http"), zap.Int("status", 200), zap.Bool("ok", true))
+				l.With(zap.String("request_id", "abc"), zap.Namespace("http")).Info("request handled", zap.String("status", "ok"))
+			},
+			Expected: []string{
+				`[level:info][msg:request handled][http:{status:200][ok:true}]`,
+				`[level:warn][msg:field schema violation][violation:key \"http.ok\" is not in the schema][entry:request handled]`,
+				`[level:info][msg:request handled][request_id:abc][http:{status:ok}]`,
+				`[level:warn][msg:field schema violation][request_id:abc][violation:key \"http.status\" must be of type int, got string][entry:request handled]`,
+			},
+		},
+		{
+			Name: "Checks With Fields Once",
+			SetupFunc: func(l log.Logger) {
+				l = l.With(zap.Int("request_id", 1))
+				l.Info("request handled")
+				l.Info("request handled")
+			},
+			Expected: []string{
+				`[level:warn][msg:field schema violation][violation:key \"request_id\" must be of type string, got int]`,
+				`[level:info][msg:request handled][request_id:1]`,
+				`[level:info][msg:request handled][request_id:1]`,
+			},
+		},
+	}
+
+	s := parse(t, testSchema)
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf zaptest.Buffer
+			cfg := log.NewProductionEncoderConfig()
+			cfg.TimeKey = ""
+			cfg.CallerKey = ""
+			core := zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &buf, zap.DebugLevel)
+
+			v := schema.NewValidator(s, tc.Config)
+			lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+			tc.SetupFunc(log.New(core, &lvl, log.WithWrapCore(v.WrapCore)))
+
+			if got := strings.Join(buf.Lines(), "\n"); got != strings.Join(tc.Expected, "\n") {
+				t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(tc.Expected, "\n"), got)
+			}
+		})
+	}
+}
+
+func TestValidatorChecksWrappedCore(t *testing.T) {
+	var infoBuf, warnBuf zaptest.Buffer
+	cfg := log.NewProductionEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.CallerKey = ""
+	core := zapcore.NewTee(
+		zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &infoBuf, zap.InfoLevel),
+		zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &warnBuf, zap.WarnLevel),
+	)
+
+	v := schema.NewValidator(parse(t, testSchema), schema.ValidatorConfig{})
+	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+	l := log.New(core, &lvl, log.WithWrapCore(v.WrapCore))
+	l.Info("request handled", zap.String("reqID", "abc"))
+
+	expected := []string{
+		`[level:warn][msg:field schema violation][violation:key \"reqID\" is an alias of \"request_id\"][entry:request handled]`,
+	}
+	if got := strings.Join(warnBuf.Lines(), "\n"); got != strings.Join(expected, "\n") {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), got)
+	}
+	if n := len(infoBuf.Lines()); n != 2 {
+		t.Fatalf("expected 2 lines in the info core, got %d", n)
+	}
+}
+
+func TestValidatorClock(t *testing.T) {
+	var buf zaptest.Buffer
+	cfg := log.NewProductionEncoderConfig()
+	cfg.CallerKey = ""
+	core := zapcore.NewCore(encoders.NewKeyValueEncoder(cfg), &buf, zap.DebugLevel)
+
+	clock := logtest.NewClock(time.Date(2019, time.April, 8, 20, 21, 32, 0, time.UTC))
+	v := schema.NewValidator(parse(t, testSchema), schema.ValidatorConfig{Clock: clock})
+	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+	l := log.New(core, &lvl, log.WithWrapCore(v.WrapCore), log.WithClock(clock))
+	l.With(fields.Lazy("requestId", func() zap.Field { return zap.String("", "abc") })).Info("request handled")
+
+	expected := []string{
+		`[ts:2019-04-08T20:21:32.000000Z][level:warn][msg:field schema violation][violation:key \"requestId\" is an alias of \"request_id\"]`,
+		`[ts:2019-04-08T20:21:32.000000Z][level:info][msg:request handled][requestId:abc]`,
+	}
+	if got := strings.Join(buf.Lines(), "\n"); got != strings.Join(expected, "\n") {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), got)
+	}
+}
+
+func TestValidatorPanic(t *testing.T) {
+	v := schema.NewValidator(parse(t, testSchema), schema.ValidatorConfig{Panic: true})
+	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+	core := zapcore.NewCore(encoders.NewKeyValueEncoder(log.NewProductionEncoderConfig()), zapcore.AddSync(ioutil.Discard), zap.DebugLevel)
+	l := log.New(core, &lvl, log.WithWrapCore(v.WrapCore))
+
+	defer func() {
+		r := recover()
+		expected := `field schema violation: key "requestId" is an alias of "request_id"`
+		if r != expected {
+			t.Fatalf("expected panic %q, got %v", expected, r)
+		}
+		if n := v.Violations(); n != 1 {
+			t.Fatalf("expected 1 violation, got %d", n)
+		}
+	}()
+	l.Info("request handled", zap.String("requestId", "abc"))
+}
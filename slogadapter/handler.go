@@ -0,0 +1,219 @@
+//go:build go1.21
+// +build go1.21
+
+// Package slogadapter bridges log/slog to the loggers of this package, so
+// code written against slog logs through the key value encoder and the
+// logger associated with its context.
+//
+//	logger := slog.New(slogadapter.NewHandler(nil))
+//	logger.InfoContext(ctx, "request done", "status", 200)
+package slogadapter
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	log "github.com/emiguens/zapfmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Handler is a slog.Handler writing records through a Logger.
+//
+// The Logger is resolved for each record from the context given to the slog
+// logger methods, such as slog.InfoContext, falling back to the one given to
+// NewHandler when the context has none. Attributes are written as fields and
+// groups as namespaces.
+type Handler struct {
+	logger log.Logger
+
+	// fields holds the attributes and groups added through WithAttrs and
+	// WithGroup, already converted.
+	fields []zapcore.Field
+
+	// groups holds the groups opened by WithGroup that don't have attributes
+	// yet, which slog omits if the record doesn't add any.
+	groups []string
+}
+
+var _ slog.Handler = &Handler{}
+
+// NewHandler returns a handler writing through the logger associated with
+// the context of each record, or through l when there's none. If l is nil,
+// log.DefaultLogger is used instead.
+func NewHandler(l log.Logger) *Handler {
+	return &Handler{logger: l}
+}
+
+// Enabled reports whether the logger of the context logs records at the given
+// level.
+func (h *Handler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	l, ok := h.resolve(ctx).(interface{ Core() zapcore.Core })
+	if !ok {
+		return true
+	}
+	return l.Core().Enabled(Level(lvl))
+}
+
+// Handle writes the record with the fields added to the handler and the
+// record attributes. The caller is taken from the record, while the time is
+// set by the logger so that log.WithClock applies.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	ce := h.resolve(ctx).Check(Level(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+	if ce.Entry.Caller.Defined && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]zapcore.Field, 0, len(h.fields)+len(h.groups)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+	if r.NumAttrs() > 0 {
+		fields = appendGroups(fields, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			fields = appendAttr(fields, a)
+			return true
+		})
+	}
+
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs returns a handler adding the given attributes to every record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	child := h.clone()
+	child.fields = appendGroups(child.fields, child.groups)
+	child.groups = nil
+	for _, a := range attrs {
+		child.fields = appendAttr(child.fields, a)
+	}
+	return child
+}
+
+// WithGroup returns a handler writing the attributes added afterwards within
+// a namespace with the given name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	child := h.clone()
+	child.groups = append(child.groups, name)
+	return child
+}
+
+func (h *Handler) clone() *Handler {
+	return &Handler{
+		logger: h.logger,
+		fields: h.fields[:len(h.fields):len(h.fields)],
+		groups: h.groups[:len(h.groups):len(h.groups)],
+	}
+}
+
+// resolve returns the logger to write the records logged with ctx.
+func (h *Handler) resolve(ctx context.Context) log.Logger {
+	if ctx != nil {
+		if l, ok := log.FromContext(ctx); ok {
+			return l
+		}
+	}
+	if h.logger != nil {
+		return h.logger
+	}
+	return log.DefaultLogger
+}
+
+// Level maps a slog level to the zap level at or below it. Levels between
+// the slog predefined ones, such as slog.LevelInfo+2, map to the lower one,
+// and levels above slog.LevelError map to zap.ErrorLevel.
+func Level(lvl slog.Level) zapcore.Level {
+	switch {
+	case lvl < slog.LevelInfo:
+		return zap.DebugLevel
+	case lvl < slog.LevelWarn:
+		return zap.InfoLevel
+	case lvl < slog.LevelError:
+		return zap.WarnLevel
+	default:
+		return zap.ErrorLevel
+	}
+}
+
+func appendGroups(fields []zapcore.Field, groups []string) []zapcore.Field {
+	for _, g := range groups {
+		fields = append(fields, zap.Namespace(g))
+	}
+	return fields
+}
+
+// appendAttr appends the fields for the given attribute, following the slog
+// rules: empty attributes are ignored and groups without a key are inlined.
+func appendAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, group(attrs)))
+	}
+
+	return append(fields, field(a))
+}
+
+// field converts a resolved attribute that is not a group.
+func field(a slog.Attr) zapcore.Field {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return zap.String(a.Key, a.Value.String())
+	case slog.KindInt64:
+		return zap.Int64(a.Key, a.Value.Int64())
+	case slog.KindUint64:
+		return zap.Uint64(a.Key, a.Value.Uint64())
+	case slog.KindFloat64:
+		return zap.Float64(a.Key, a.Value.Float64())
+	case slog.KindBool:
+		return zap.Bool(a.Key, a.Value.Bool())
+	case slog.KindDuration:
+		return zap.Duration(a.Key, a.Value.Duration())
+	case slog.KindTime:
+		return zap.Time(a.Key, a.Value.Time())
+	}
+
+	if err, ok := a.Value.Any().(error); ok {
+		return zap.NamedError(a.Key, err)
+	}
+	return zap.Any(a.Key, a.Value.Any())
+}
+
+// group writes the attributes of a slog group as an object.
+type group []slog.Attr
+
+func (g group) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	var fields []zapcore.Field
+	for _, a := range g {
+		fields = appendAttr(fields, a)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return nil
+}
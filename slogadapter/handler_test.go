@@ -0,0 +1,122 @@
+//go:build go1.21
+// +build go1.21
+
+package slogadapter_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/logtest"
+	"github.com/emiguens/zapfmt/slogadapter"
+	"go.uber.org/zap"
+)
+
+func TestHandler(t *testing.T) {
+	tt := []struct {
+		Name      string
+		SetupFunc func(ctx context.Context, l *slog.Logger)
+		Expected  []string
+	}{
+		{
+			Name: "Levels",
+			SetupFunc: func(ctx context.Context, l *slog.Logger) {
+				l.DebugContext(ctx, "my Debug message")
+				l.InfoContext(ctx, "my Info message")
+				l.Log(ctx, slog.LevelInfo+2, "my custom message")
+				l.WarnContext(ctx, "my Warn message")
+				l.Log(ctx, slog.LevelError+4, "my Error message")
+			},
+			Expected: []string{
+				"[level:debug][caller:zapfmt/caller.go:1][msg:my Debug message]",
+				"[level:info][caller:zapfmt/caller.go:1][msg:my Info message]",
+				"[level:info][caller:zapfmt/caller.go:1][msg:my custom message]",
+				"[level:warn][caller:zapfmt/caller.go:1][msg:my Warn message]",
+				"[level:error][caller:zapfmt/caller.go:1][msg:my Error message][stacktrace:<stacktrace>]",
+			},
+		},
+		{
+			Name: "Attributes",
+			SetupFunc: func(ctx context.Context, l *slog.Logger) {
+				l.InfoContext(ctx, "my Info message",
+					"string_key", "value",
+					"int_key", -1,
+					"uint_key", uint64(2),
+					"float_key", 1.5,
+					"bool_key", true,
+					"duration_key", 374*time.Millisecond,
+					"time_key", time.Unix(0, 0),
+					"error", errors.New("my error"),
+					slog.Group("group", "a", 1, "b", 2),
+					slog.Group("", "inlined", "yes"),
+					slog.Group("empty"),
+				)
+			},
+			Expected: []string{
+				"[level:info][caller:zapfmt/caller.go:1][msg:my Info message][string_key:value][int_key:-1][uint_key:2][float_key:1.5][bool_key:true][duration_key:0.374][time_key:1970-01-01T00:00:00.000000Z][error:my error][group:{a:1][b:2}][inlined:yes]",
+			},
+		},
+		{
+			Name: "With Attributes And Groups",
+			SetupFunc: func(ctx context.Context, l *slog.Logger) {
+				l = l.With("request_id", "abc").WithGroup("http")
+				l.InfoContext(ctx, "no attributes")
+				l.With("method", "GET").InfoContext(ctx, "my Info message", "status", 200)
+			},
+			Expected: []string{
+				"[level:info][caller:zapfmt/caller.go:1][msg:no attributes][request_id:abc]",
+				"[level:info][caller:zapfmt/caller.go:1][msg:my Info message][request_id:abc][http:{method:GET][status:200}]",
+			},
+		},
+		{
+			Name: "Context Logger",
+			SetupFunc: func(ctx context.Context, l *slog.Logger) {
+				ctx = log.Named(ctx, "first_level")
+				ctx = log.With(ctx, zap.String("request_id", "abc"))
+				l.InfoContext(ctx, "my Info message")
+				l.Info("without context")
+			},
+			Expected: []string{
+				"[level:info][logger:first_level][caller:zapfmt/caller.go:1][msg:my Info message][request_id:abc]",
+				"[level:info][caller:zapfmt/caller.go:1][msg:without context]",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			logger, golden := logtest.NewGolden(t, zap.DebugLevel)
+			ctx := log.Context(context.Background(), logger)
+
+			tc.SetupFunc(ctx, slog.New(slogadapter.NewHandler(logger)))
+
+			expected := ""
+			for _, line := range tc.Expected {
+				expected += "[ts:2019-04-08T20:21:32.375067Z]" + line + "\n"
+			}
+			if golden.String() != expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", expected, golden.String())
+			}
+		})
+	}
+}
+
+func TestHandlerCaller(t *testing.T) {
+	ctx, logs := logtest.NewContext(context.Background(), zap.InfoLevel)
+	l := slog.New(slogadapter.NewHandler(nil))
+
+	l.DebugContext(ctx, "my Debug message")
+	l.InfoContext(ctx, "my Info message")
+
+	if logs.Len() != 1 {
+		t.Fatalf("expected 1 entry, got: %d", logs.Len())
+	}
+	if file := filepath.Base(logs.All()[0].Caller.File); file != "handler_test.go" {
+		t.Fatalf("expected caller in handler_test.go, got: %s", file)
+	}
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+
+	"github.com/emiguens/zapfmt/internal/caller"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedirectStdLog redirects the output of the standard library's package-global
+// logger to the given logger at the given level, and returns a function to
+// restore the original prefix, flags and output.
+//
+// Entries are attributed to the code calling the standard library logger.
+// Since the standard library logger is global, RedirectStdLog is meant to be
+// called once when the application starts:
+//
+//	undo := log.RedirectStdLog(logger, zap.InfoLevel)
+//	defer undo()
+func RedirectStdLog(l Logger, lvl zapcore.Level) func() {
+	flags := stdlog.Flags()
+	prefix := stdlog.Prefix()
+	out := stdlog.Writer()
+
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&stdLogWriter{logger: l, lvl: lvl})
+
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(out)
+	}
+}
+
+// stdLogWriter writes each line of the standard library logger as an entry.
+type stdLogWriter struct {
+	logger Logger
+	lvl    zapcore.Level
+}
+
+func (w *stdLogWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	msg := string(bytes.TrimSuffix(b, []byte("\n")))
+
+	if ce := w.logger.Check(w.lvl, msg); ce != nil {
+		if ce.Entry.Caller.Defined {
+			ce.Entry.Caller = caller.Find(0, "log.", "github.com/emiguens/zapfmt.(*stdLogWriter).")
+		}
+		ce.Write()
+	}
+	return n, nil
+}
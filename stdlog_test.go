@@ -0,0 +1,42 @@
+package log_test
+
+import (
+	"context"
+	stdlog "log"
+	"path/filepath"
+	"testing"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/logtest"
+	"go.uber.org/zap"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	ctx, logs := logtest.NewContext(context.Background(), zap.DebugLevel)
+	ctx = log.Named(ctx, "stdlib")
+	l, _ := log.FromContext(ctx)
+
+	flags, prefix := stdlog.Flags(), stdlog.Prefix()
+	undo := log.RedirectStdLog(l, zap.WarnLevel)
+	stdlog.Printf("my %s message", "Printf")
+	stdlog.Println("my Println message")
+	undo()
+
+	if stdlog.Flags() != flags || stdlog.Prefix() != prefix {
+		t.Fatalf("expected flags and prefix to be restored")
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got: %d", len(entries))
+	}
+	for i, msg := range []string{"my Printf message", "my Println message"} {
+		e := entries[i]
+		if e.Message != msg || e.Level != zap.WarnLevel || e.LoggerName != "stdlib" {
+			t.Fatalf("unexpected entry: %+v", e.Entry)
+		}
+		if file := filepath.Base(e.Caller.File); file != "stdlog_test.go" {
+			t.Fatalf("expected caller in stdlog_test.go, got: %s", e.Caller.File)
+		}
+	}
+}
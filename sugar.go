@@ -0,0 +1,80 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/emiguens/zapfmt/internal/kv"
+	"go.uber.org/zap"
+)
+
+// The functions below call Check themselves rather than sharing a helper, so
+// that the caller is found at the same depth as for Debug, Info and so on.
+// Messages are only formatted if the entry is going to be logged.
+
+// Debugf formats the message with fmt.Sprintf and logs it at DebugLevel.
+func Debugf(ctx context.Context, template string, args ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.DebugLevel, template); ce != nil {
+		ce.Entry.Message = fmt.Sprintf(template, args...)
+		ce.Write()
+	}
+}
+
+// Infof formats the message with fmt.Sprintf and logs it at InfoLevel.
+func Infof(ctx context.Context, template string, args ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.InfoLevel, template); ce != nil {
+		ce.Entry.Message = fmt.Sprintf(template, args...)
+		ce.Write()
+	}
+}
+
+// Warnf formats the message with fmt.Sprintf and logs it at WarnLevel.
+func Warnf(ctx context.Context, template string, args ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.WarnLevel, template); ce != nil {
+		ce.Entry.Message = fmt.Sprintf(template, args...)
+		ce.Write()
+	}
+}
+
+// Errorf formats the message with fmt.Sprintf and logs it at ErrorLevel.
+func Errorf(ctx context.Context, template string, args ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.ErrorLevel, template); ce != nil {
+		ce.Entry.Message = fmt.Sprintf(template, args...)
+		ce.Write()
+	}
+}
+
+// Debugw logs a message at DebugLevel with some additional context. The
+// variadic key value pairs are treated as they are in With, zap fields may
+// be given in place of a pair.
+//
+//	log.Debugw(ctx, "cache miss", "key", key, zap.Duration("elapsed", elapsed))
+func Debugw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.DebugLevel, msg); ce != nil {
+		ce.Write(kv.Fields(keysAndValues)...)
+	}
+}
+
+// Infow logs a message at InfoLevel with some additional context. The
+// variadic key value pairs are treated as they are in Debugw.
+func Infow(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.InfoLevel, msg); ce != nil {
+		ce.Write(kv.Fields(keysAndValues)...)
+	}
+}
+
+// Warnw logs a message at WarnLevel with some additional context. The
+// variadic key value pairs are treated as they are in Debugw.
+func Warnw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.WarnLevel, msg); ce != nil {
+		ce.Write(kv.Fields(keysAndValues)...)
+	}
+}
+
+// Errorw logs a message at ErrorLevel with some additional context. The
+// variadic key value pairs are treated as they are in Debugw.
+func Errorw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := getLogger(ctx).Check(zap.ErrorLevel, msg); ce != nil {
+		ce.Write(kv.Fields(keysAndValues)...)
+	}
+}
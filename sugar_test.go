@@ -0,0 +1,164 @@
+package log_test
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/logtest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// countingStringer counts how many times it's formatted.
+type countingStringer struct {
+	n *int
+}
+
+func (s countingStringer) String() string {
+	*s.n++
+	return "value"
+}
+
+func TestSugar(t *testing.T) {
+	tt := []struct {
+		Name      string
+		LogFunc   func(ctx context.Context, arg interface{})
+		Level     zapcore.Level
+		Message   string
+		Fields    map[string]interface{}
+		Formatted bool
+	}{
+		{
+			Name:    "Debugf Disabled",
+			LogFunc: func(ctx context.Context, arg interface{}) { log.Debugf(ctx, "my %s message", arg) },
+		},
+		{
+			Name:      "Infof",
+			LogFunc:   func(ctx context.Context, arg interface{}) { log.Infof(ctx, "my %s message", arg) },
+			Level:     zap.InfoLevel,
+			Message:   "my value message",
+			Formatted: true,
+		},
+		{
+			Name:      "Warnf",
+			LogFunc:   func(ctx context.Context, arg interface{}) { log.Warnf(ctx, "my %s message", arg) },
+			Level:     zap.WarnLevel,
+			Message:   "my value message",
+			Formatted: true,
+		},
+		{
+			Name:      "Errorf",
+			LogFunc:   func(ctx context.Context, arg interface{}) { log.Errorf(ctx, "my %s message", arg) },
+			Level:     zap.ErrorLevel,
+			Message:   "my value message",
+			Formatted: true,
+		},
+		{
+			Name:    "Debugw Disabled",
+			LogFunc: func(ctx context.Context, arg interface{}) { log.Debugw(ctx, "my message", "key", arg) },
+		},
+		{
+			Name: "Infow",
+			LogFunc: func(ctx context.Context, arg interface{}) {
+				log.Infow(ctx, "my message", "key", 1, zap.String("field", "value"))
+			},
+			Level:   zap.InfoLevel,
+			Message: "my message",
+			Fields:  map[string]interface{}{"key": int64(1), "field": "value"},
+		},
+		{
+			Name:    "Warnw",
+			LogFunc: func(ctx context.Context, arg interface{}) { log.Warnw(ctx, "my message", "key", "value", "dangling") },
+			Level:   zap.WarnLevel,
+			Message: "my message",
+			Fields:  map[string]interface{}{"key": "value", "!BADKEY": "dangling"},
+		},
+		{
+			Name:    "Errorw",
+			LogFunc: func(ctx context.Context, arg interface{}) { log.Errorw(ctx, "my message", "key", true) },
+			Level:   zap.ErrorLevel,
+			Message: "my message",
+			Fields:  map[string]interface{}{"key": true},
+		},
+		{
+			Name:    "Sugar",
+			LogFunc: func(ctx context.Context, arg interface{}) { log.Sugar(ctx).Infow("my message", "key", "value") },
+			Level:   zap.InfoLevel,
+			Message: "my message",
+			Fields:  map[string]interface{}{"key": "value"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx, logs := logtest.NewContext(context.Background(), zap.InfoLevel)
+
+			var formatted int
+			_, _, line, _ := runtime.Caller(0)
+			tc.LogFunc(ctx, countingStringer{&formatted})
+
+			if tc.Formatted != (formatted > 0) {
+				t.Fatalf("expected formatting to be %v, formatted %d times", tc.Formatted, formatted)
+			}
+
+			entries := logs.All()
+			if tc.Message == "" {
+				if len(entries) != 0 {
+					t.Fatalf("expected no entries, got: %v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 entry, got: %d", len(entries))
+			}
+
+			e := entries[0]
+			if e.Level != tc.Level || e.Message != tc.Message {
+				t.Fatalf("expected %s entry %q, got %s entry %q", tc.Level, tc.Message, e.Level, e.Message)
+			}
+			if tc.Fields != nil {
+				fields := e.FieldMap()
+				for k, v := range tc.Fields {
+					if fields[k] != v {
+						t.Fatalf("expected field %s to be %v, got: %v", k, v, fields)
+					}
+				}
+			}
+
+			// The entry is attributed to the test case function, declared
+			// above the loop.
+			if file := filepath.Base(e.Caller.File); file != "sugar_test.go" || e.Caller.Line >= line {
+				t.Fatalf("expected caller in a test case of sugar_test.go, got: %s", e.Caller)
+			}
+		})
+	}
+}
+
+// zapLogger is a Logger built with zap.New, which doesn't skip any frame.
+type zapLogger struct {
+	*zap.Logger
+}
+
+func (l zapLogger) Named(s string) log.Logger              { return zapLogger{l.Logger.Named(s)} }
+func (l zapLogger) With(fields ...zap.Field) log.Logger    { return zapLogger{l.Logger.With(fields...)} }
+func (l zapLogger) WithLevel(lvl zapcore.Level) log.Logger { return l }
+
+func TestSugarOtherLoggers(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := log.Context(context.Background(), zapLogger{zap.New(core, zap.AddCaller())})
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Sugar(ctx).Info("my message")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got: %d", len(entries))
+	}
+	if c := entries[0].Caller; filepath.Base(c.File) != "sugar_test.go" || c.Line != line+1 {
+		t.Fatalf("expected caller at sugar_test.go:%d, got: %s", line+1, c)
+	}
+}
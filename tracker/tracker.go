@@ -0,0 +1,473 @@
+// Package tracker forwards log entries to an error tracker speaking the
+// Sentry protocol, such as Sentry itself or a compatible service.
+//
+// Entries at the configured level and above are turned into events, queued,
+// and sent in batches by a background goroutine, so logging never waits on
+// the tracker, except for entries above ErrorLevel, which are sent right away
+// before the program may exit, for up to Config.FlushTimeout:
+//
+//	t, err := tracker.New(tracker.Config{DSN: os.Getenv("SENTRY_DSN")})
+//	if err != nil {
+//		return err
+//	}
+//	defer t.Close()
+//
+//	logger := log.NewProductionLogger(&lvl, log.WithWrapCore(t.WrapCore))
+package tracker
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultBatchSize is the number of queued events that wakes the sender
+	// up before the flush interval, unless Config.BatchSize says otherwise.
+	// Each event is still sent in an envelope of its own.
+	DefaultBatchSize = 100
+
+	// DefaultFlushInterval is how often queued events are sent, unless
+	// Config.FlushInterval says otherwise.
+	DefaultFlushInterval = time.Second
+
+	// DefaultQueueSize is the maximum number of events waiting to be sent,
+	// unless Config.QueueSize says otherwise.
+	DefaultQueueSize = 1000
+
+	// DefaultFlushTimeout is how long entries above ErrorLevel wait for the
+	// queued events to be sent, unless Config.FlushTimeout says otherwise.
+	DefaultFlushTimeout = 5 * time.Second
+
+	// DefaultTimeout is the timeout of the client sending the events, unless
+	// Config.Client says otherwise.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config configures a Tracker.
+type Config struct {
+	// DSN is the tracker Data Source Name, as in
+	// https://<key>@<host>/<project>.
+	DSN string
+
+	// Level enables the forwarded entries, such as a zapcore.Level or a
+	// zap.AtomicLevel. If nil, entries at ErrorLevel and above are forwarded.
+	Level zapcore.LevelEnabler
+
+	// BatchSize, FlushInterval and QueueSize default to DefaultBatchSize,
+	// DefaultFlushInterval and DefaultQueueSize. Entries logged while the
+	// queue is full are dropped.
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+
+	// FlushTimeout bounds how long logging an entry above ErrorLevel waits
+	// for the queued events to be sent, DefaultFlushTimeout if zero. Events
+	// not sent by then are still sent in the background.
+	FlushTimeout time.Duration
+
+	// Client sends the events, a client timing out after DefaultTimeout if
+	// nil.
+	Client *http.Client
+
+	// OnError, if not nil, is called by the background goroutine with the
+	// errors encoding or sending events.
+	OnError func(error)
+}
+
+// Tracker sends events to an error tracker. All methods are safe for
+// concurrent use.
+type Tracker struct {
+	cfg      Config
+	endpoint string
+	auth     string
+
+	queue   chan *Event
+	flushes chan chan struct{}
+	done    chan struct{}
+	closed  sync.Once
+
+	dropped uint64
+}
+
+// New returns a tracker sending events to the tracker identified by the DSN
+// given in the configuration, and starts its background goroutine. Close
+// must be called to send the queued events and stop it.
+func New(cfg Config) (*Tracker, error) {
+	u, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: invalid DSN: %v", err)
+	}
+	project := path.Base(u.Path)
+	if u.User == nil || u.User.Username() == "" || project == "." || project == "/" {
+		return nil, errors.New("tracker: invalid DSN: it must look like https://<key>@<host>/<project>")
+	}
+
+	if cfg.Level == nil {
+		cfg.Level = zap.ErrorLevel
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = DefaultFlushTimeout
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	prefix := strings.TrimSuffix(path.Dir(u.Path), "/")
+	t := &Tracker{
+		cfg:      cfg,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=zapfmt/1.0, sentry_key=%s", u.User.Username()),
+		queue:    make(chan *Event, cfg.QueueSize),
+		flushes:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t, nil
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (t *Tracker) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+// Flush sends the queued events, returning once they were sent.
+func (t *Tracker) Flush() {
+	t.flush(nil)
+}
+
+// flush sends the queued events, returning once they were sent or once
+// timeout fires, if not nil.
+func (t *Tracker) flush(timeout <-chan time.Time) {
+	done := make(chan struct{})
+	select {
+	case t.flushes <- done:
+	case <-t.done:
+		return
+	case <-timeout:
+		return
+	}
+
+	select {
+	case <-done:
+	case <-timeout:
+	}
+}
+
+// Close sends the queued events and stops the background goroutine. Entries
+// logged afterwards are dropped.
+func (t *Tracker) Close() error {
+	t.closed.Do(func() {
+		t.Flush()
+		close(t.done)
+	})
+	return nil
+}
+
+// enqueue queues the event, dropping it if the queue is full or the tracker
+// is closed.
+func (t *Tracker) enqueue(e *Event) {
+	select {
+	case <-t.done:
+		atomic.AddUint64(&t.dropped, 1)
+		return
+	default:
+	}
+
+	select {
+	case t.queue <- e:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+func (t *Tracker) run() {
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Event, 0, t.cfg.BatchSize)
+	send := func() {
+		for _, e := range batch {
+			t.send(e)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case e := <-t.queue:
+			batch = append(batch, e)
+			if len(batch) == t.cfg.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-t.flushes:
+			for n := len(t.queue); n > 0; n-- {
+				batch = append(batch, <-t.queue)
+			}
+			send()
+			close(done)
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// send posts the event to the envelope endpoint of the tracker, reporting
+// the errors to OnError.
+func (t *Tracker) send(e *Event) {
+	payload, err := encode(e)
+	if err != nil {
+		t.report(err)
+	}
+	if payload == nil {
+		return
+	}
+	if err := t.post(envelope(e.EventID, payload)); err != nil {
+		t.report(err)
+	}
+}
+
+// post posts an envelope to the tracker.
+func (t *Tracker) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("tracker: sending event: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", t.auth)
+
+	resp, err := t.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("tracker: sending event: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("tracker: sending event: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// report calls OnError, if any, with err.
+func (t *Tracker) report(err error) {
+	if t.cfg.OnError != nil {
+		t.cfg.OnError(err)
+	}
+}
+
+// encode encodes the event as JSON. Extra values that can't be encoded, such
+// as NaN floats or channels, are encoded as their text instead, returning
+// the error they caused along with the payload.
+func encode(e *Event) ([]byte, error) {
+	payload, err := json.Marshal(e)
+	if err == nil {
+		return payload, nil
+	}
+
+	extra := make(map[string]interface{}, len(e.Extra))
+	for k, v := range e.Extra {
+		if _, verr := json.Marshal(v); verr != nil {
+			v = fmt.Sprint(v)
+		}
+		extra[k] = v
+	}
+	sanitized := *e
+	sanitized.Extra = extra
+
+	payload, serr := json.Marshal(&sanitized)
+	if serr != nil {
+		return nil, fmt.Errorf("tracker: encoding event %s: %v", e.EventID, serr)
+	}
+	return payload, fmt.Errorf("tracker: encoding event %s, sent extra values as text: %v", e.EventID, err)
+}
+
+// envelope returns an envelope holding the encoded event: a header line
+// followed by the item header and payload lines of the event. Envelopes hold
+// a single event.
+func envelope(eventID string, payload []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{\"event_id\":%q,\"sent_at\":%q}\n", eventID, time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&buf, "{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// Event is an event as sent to the tracker.
+type Event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Platform    string                 `json:"platform"`
+	Logger      string                 `json:"logger,omitempty"`
+	Message     string                 `json:"message"`
+	Culprit     string                 `json:"culprit,omitempty"`
+	Fingerprint []string               `json:"fingerprint"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Exception   []Exception            `json:"exception,omitempty"`
+}
+
+// Exception describes an error field of the entry.
+type Exception struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// WrapCore returns a core writing to the given one, and forwarding the
+// entries at the configured level and above to the tracker. It's meant to be
+// given to log.WithWrapCore.
+//
+// Fields added through With are sent as tags, and the ones given at the log
+// site as extra data, except for errors, which are sent as exceptions.
+func (t *Tracker) WrapCore(core zapcore.Core) zapcore.Core {
+	return zapcore.NewTee(core, &trackerCore{t: t})
+}
+
+// trackerCore turns entries into events.
+type trackerCore struct {
+	t    *Tracker
+	tags map[string]string
+}
+
+func (c *trackerCore) Enabled(lvl zapcore.Level) bool {
+	return c.t.cfg.Level.Enabled(lvl)
+}
+
+func (c *trackerCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	tags := make(map[string]string, len(c.tags)+len(enc.Fields))
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	for k, v := range enc.Fields {
+		tags[k] = fmt.Sprint(v)
+	}
+	return &trackerCore{t: c.t, tags: tags}
+}
+
+func (c *trackerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *trackerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+
+	e := &Event{
+		EventID:     eventID(),
+		Timestamp:   ent.Time.UTC().Format(time.RFC3339Nano),
+		Level:       level(ent.Level),
+		Platform:    "go",
+		Logger:      ent.LoggerName,
+		Message:     ent.Message,
+		Fingerprint: Fingerprint(ent),
+		Tags:        c.tags,
+	}
+	if ent.Caller.Defined {
+		e.Culprit = ent.Caller.TrimmedPath()
+	}
+
+	// Fields are encoded right away, as they're owned by the caller.
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType {
+			if err, ok := f.Interface.(error); ok {
+				e.Exception = append(e.Exception, Exception{
+					Type:  fmt.Sprintf("%T", err),
+					Value: err.Error(),
+				})
+				continue
+			}
+		}
+		f.AddTo(enc)
+	}
+	if ent.Stack != "" {
+		enc.Fields["stacktrace"] = ent.Stack
+	}
+	if len(enc.Fields) > 0 {
+		e.Extra = enc.Fields
+	}
+
+	c.t.enqueue(e)
+
+	// Entries above ErrorLevel are usually followed by a panic or an exit,
+	// which would lose the queued events.
+	if ent.Level > zapcore.ErrorLevel {
+		timer := time.NewTimer(c.t.cfg.FlushTimeout)
+		c.t.flush(timer.C)
+		timer.Stop()
+	}
+	return nil
+}
+
+// Sync sends the queued events.
+func (c *trackerCore) Sync() error {
+	c.t.Flush()
+	return nil
+}
+
+// Fingerprint returns the fingerprint grouping the events of an entry in the
+// tracker: its message and caller. Entries logged from the same place with
+// the same message are grouped together, no matter their fields.
+func Fingerprint(ent zapcore.Entry) []string {
+	if !ent.Caller.Defined {
+		return []string{ent.Message}
+	}
+	return []string{ent.Message, ent.Caller.TrimmedPath()}
+}
+
+// level returns the tracker name of a level.
+func level(lvl zapcore.Level) string {
+	switch lvl {
+	case zap.DebugLevel:
+		return "debug"
+	case zap.InfoLevel:
+		return "info"
+	case zap.WarnLevel:
+		return "warning"
+	case zap.ErrorLevel:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// eventID returns a random event identifier.
+func eventID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
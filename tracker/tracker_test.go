@@ -0,0 +1,200 @@
+package tracker_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/emiguens/zapfmt"
+	"github.com/emiguens/zapfmt/tracker"
+	"github.com/emiguens/zapfmt/tracker/trackertest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newContext(t *testing.T, cfg tracker.Config) (context.Context, *tracker.Tracker) {
+	tr, err := tracker.New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating tracker: %v", err)
+	}
+
+	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
+	logger := log.New(zapcore.NewNopCore(), &lvl, log.WithWrapCore(tr.WrapCore))
+	return log.Context(context.Background(), logger), tr
+}
+
+func TestTracker(t *testing.T) {
+	srv := trackertest.NewServer()
+	defer srv.Close()
+
+	ctx, tr := newContext(t, tracker.Config{DSN: srv.DSN(), Level: zap.ErrorLevel})
+
+	ctx = log.Named(ctx, "http")
+	ctx = log.With(ctx, zap.String("request_id", "abc"), zap.Int("attempt", 2))
+	log.Warn(ctx, "not forwarded")
+	for i := 0; i < 2; i++ {
+		log.Error(ctx, "request failed", zap.Error(errors.New("connection refused")), zap.Int("status", 502))
+	}
+	log.Error(ctx, "other failure")
+	tr.Close()
+
+	events := srv.Events()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got: %d", len(events))
+	}
+
+	e := events[0]
+	if e.Level != "error" || e.Logger != "http" || e.Message != "request failed" || e.Platform != "go" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if e.Tags["request_id"] != "abc" || e.Tags["attempt"] != "2" {
+		t.Fatalf("unexpected tags: %v", e.Tags)
+	}
+	if e.Extra["status"] != float64(502) || e.Extra["stacktrace"] == nil {
+		t.Fatalf("unexpected extra: %v", e.Extra)
+	}
+	if len(e.Exception) != 1 || e.Exception[0].Type != "*errors.errorString" || e.Exception[0].Value != "connection refused" {
+		t.Fatalf("unexpected exception: %v", e.Exception)
+	}
+	if !strings.HasPrefix(e.Culprit, "tracker/tracker_test.go:") {
+		t.Fatalf("unexpected culprit: %s", e.Culprit)
+	}
+	if e.EventID == "" || e.EventID == events[1].EventID {
+		t.Fatalf("expected unique event ids, got: %s and %s", e.EventID, events[1].EventID)
+	}
+
+	if strings.Join(e.Fingerprint, "|") != strings.Join(events[1].Fingerprint, "|") {
+		t.Fatalf("expected equal fingerprints, got: %v and %v", e.Fingerprint, events[1].Fingerprint)
+	}
+	if strings.Join(e.Fingerprint, "|") == strings.Join(events[2].Fingerprint, "|") {
+		t.Fatalf("expected different fingerprints, got: %v", e.Fingerprint)
+	}
+
+	if auth := srv.Auths()[0]; !strings.Contains(auth, "sentry_key="+trackertest.Key) {
+		t.Fatalf("unexpected auth header: %s", auth)
+	}
+
+	log.Error(ctx, "after close")
+	if tr.Dropped() != 1 {
+		t.Fatalf("expected 1 dropped event, got: %d", tr.Dropped())
+	}
+}
+
+func TestTrackerBatches(t *testing.T) {
+	srv := trackertest.NewServer()
+	defer srv.Close()
+
+	ctx, tr := newContext(t, tracker.Config{
+		DSN:           srv.DSN(),
+		Level:         zap.ErrorLevel,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	defer tr.Close()
+
+	log.Error(ctx, "first")
+	log.Error(ctx, "second")
+	log.Error(ctx, "third")
+
+	if events := srv.WaitEvents(2, time.Second); len(events) != 2 {
+		t.Fatalf("expected a full batch to be sent, got %d events", len(events))
+	}
+	if n := len(srv.Auths()); n != 2 {
+		t.Fatalf("expected one request per event, got %d", n)
+	}
+
+	tr.Flush()
+	if events := srv.Events(); len(events) != 3 {
+		t.Fatalf("expected flush to send the queued event, got %d events", len(events))
+	}
+}
+
+func TestTrackerLevel(t *testing.T) {
+	srv := trackertest.NewServer()
+	defer srv.Close()
+
+	ctx, tr := newContext(t, tracker.Config{DSN: srv.DSN(), FlushInterval: time.Hour})
+	defer tr.Close()
+
+	log.Warn(ctx, "not forwarded")
+	log.Error(ctx, "queued")
+	if events := srv.Events(); len(events) != 0 {
+		t.Fatalf("expected error events to be queued, got %d events", len(events))
+	}
+
+	log.DPanic(ctx, "sent right away")
+	events := srv.Events()
+	if len(events) != 2 || events[0].Message != "queued" || events[1].Message != "sent right away" {
+		t.Fatalf("expected the queued events to be sent along with the dpanic one, got: %+v", events)
+	}
+
+	core := tr.WrapCore(zapcore.NewNopCore())
+	core.Write(zapcore.Entry{Level: zap.InfoLevel, Message: "not enabled"}, nil)
+	tr.Flush()
+	if events := srv.Events(); len(events) != 2 {
+		t.Fatalf("expected disabled entries not to be forwarded, got %d events", len(events))
+	}
+}
+
+func TestTrackerEncoding(t *testing.T) {
+	srv := trackertest.NewServer()
+	defer srv.Close()
+
+	var errs []error
+	ctx, tr := newContext(t, tracker.Config{
+		DSN:     srv.DSN(),
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+
+	log.Error(ctx, "not a number", zap.Float64("ratio", math.NaN()), zap.Int("status", 502))
+	log.Error(ctx, "valid")
+	tr.Close()
+
+	events := srv.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got: %d", len(events))
+	}
+	if events[0].Extra["ratio"] != "NaN" || events[0].Extra["status"] != float64(502) {
+		t.Fatalf("expected the NaN value sent as text, got: %v", events[0].Extra)
+	}
+	if events[1].Message != "valid" {
+		t.Fatalf("unexpected event: %+v", events[1])
+	}
+	if n := len(srv.Auths()); n != 2 {
+		t.Fatalf("expected one request per event, got %d", n)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "NaN") {
+		t.Fatalf("expected the encoding error to be reported, got: %v", errs)
+	}
+}
+
+func TestTrackerFlushTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	dsn := strings.Replace(srv.URL, "://", "://key@", 1) + "/1"
+	ctx, _ := newContext(t, tracker.Config{DSN: dsn, FlushTimeout: 10 * time.Millisecond})
+
+	start := time.Now()
+	log.DPanic(ctx, "tracker not responding")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the flush to give up after the timeout, took %v", elapsed)
+	}
+}
+
+func TestInvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://host/1", "https://key@host", "://"} {
+		if _, err := tracker.New(tracker.Config{DSN: dsn}); err == nil {
+			t.Fatalf("expected error for DSN %q", dsn)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package trackertest provides a local stand-in for an error tracker, to
+// test the events sent by a tracker.Tracker without an external service.
+package trackertest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emiguens/zapfmt/tracker"
+)
+
+// Key and Project are the ones in the DSN of every Server.
+const (
+	Key     = "public"
+	Project = "1"
+)
+
+// Server records the events posted to its envelope endpoint.
+type Server struct {
+	srv *httptest.Server
+
+	mu     sync.Mutex
+	events []tracker.Event
+	auths  []string
+}
+
+// NewServer starts a server. Close must be called to shut it down.
+func NewServer() *Server {
+	s := &Server{}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.envelope))
+	return s
+}
+
+// DSN returns the DSN to configure a tracker sending events to the server.
+func (s *Server) DSN() string {
+	return strings.Replace(s.srv.URL, "://", "://"+Key+"@", 1) + "/" + Project
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Events returns the events received so far.
+func (s *Server) Events() []tracker.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make([]tracker.Event, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+// Auths returns the X-Sentry-Auth headers of the requests received so far,
+// one per request.
+func (s *Server) Auths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	auths := make([]string, len(s.auths))
+	copy(auths, s.auths)
+	return auths
+}
+
+// WaitEvents waits until the server received n events or the timeout
+// expires, and returns the events received.
+func (s *Server) WaitEvents(n int, timeout time.Duration) []tracker.Event {
+	deadline := time.Now().Add(timeout)
+	for {
+		events := s.Events()
+		if len(events) >= n || time.Now().After(deadline) {
+			return events
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *Server) envelope(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/api/"+Project+"/envelope/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	events, err := readEnvelope(bufio.NewReader(r.Body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.events = append(s.events, events...)
+	s.auths = append(s.auths, r.Header.Get("X-Sentry-Auth"))
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// readEnvelope decodes the events of an envelope: a header line followed by
+// an item header line and a payload of the length it gives per item. Like
+// Sentry, it rejects envelopes holding more than one event.
+func readEnvelope(r *bufio.Reader) ([]tracker.Event, error) {
+	if _, err := r.ReadBytes('\n'); err != nil {
+		return nil, fmt.Errorf("reading envelope header: %v", err)
+	}
+
+	var events []tracker.Event
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading item header: %v", err)
+		}
+
+		var item struct {
+			Type   string `json:"type"`
+			Length int    `json:"length"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("decoding item header: %v", err)
+		}
+
+		payload := make([]byte, item.Length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, fmt.Errorf("reading item payload: %v", err)
+		}
+		if b, err := r.ReadByte(); err == nil && b != '\n' {
+			r.UnreadByte()
+		}
+
+		if item.Type != "event" {
+			continue
+		}
+		if len(events) > 0 {
+			return nil, errors.New("envelope holds more than one event")
+		}
+		var e tracker.Event
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("decoding event: %v", err)
+		}
+		events = append(events, e)
+	}
+}